@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+// maxOpenFiles is the number of archive files kept open at once, which
+// only needs to cover every source for a day or two
+const maxOpenFiles = 16
+
+// archiver writes snapshots to AVRO container files partitioned by date
+// and source, as <dir>/<yyyy-mm-dd>/<source>.avro
+//
+// A file written with an earlier snapshot schema is left as it is, and the
+// partition continues in <source>-<n>.avro alongside it.
+type archiver struct {
+	Dir   string
+	mu    sync.Mutex
+	files map[string]*archiveFile
+}
+
+type archiveFile struct {
+	path string
+	f    *os.File
+	cw   *gauge.ContainerWriter
+}
+
+func newArchiver(dir string) *archiver {
+	return &archiver{
+		Dir:   dir,
+		files: make(map[string]*archiveFile),
+	}
+}
+
+// Write appends a snapshot to its partition file
+//
+// Each snapshot is written out as its own block before returning so
+// that it is never acknowledged and then lost.
+func (a *archiver) Write(s *gauge.Snapshot) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	path := a.partition(s)
+	af, err := a.open(path)
+	if err != nil {
+		return path, err
+	}
+	if err := af.cw.Write(s); err != nil {
+		return af.path, err
+	}
+	return af.path, af.cw.Flush()
+}
+
+// Close closes all open archive files
+func (a *archiver) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var firstErr error
+	for path, af := range a.files {
+		if err := af.f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(a.files, path)
+	}
+	return firstErr
+}
+
+func (a *archiver) partition(s *gauge.Snapshot) string {
+	t := s.ProcessedTime
+	if t.IsZero() || t.Unix() == 0 {
+		t = time.Now()
+	}
+	return filepath.Join(a.Dir, t.UTC().Format("2006-01-02"), source(s.Station)+".avro")
+}
+
+func (a *archiver) open(path string) (*archiveFile, error) {
+	if af, ok := a.files[path]; ok {
+		return af, nil
+	}
+
+	// the partitions in use change slowly, so simply start
+	// again if too many files are open
+	if len(a.files) >= maxOpenFiles {
+		for p, af := range a.files {
+			af.f.Close()
+			delete(a.files, p)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	// skip past any files of the partition written with an earlier schema
+	for n := 0; ; n++ {
+		af, err := openArchiveFile(numbered(path, n))
+		if err == gauge.ErrSchemaChanged {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		a.files[path] = af
+		return af, nil
+	}
+}
+
+// openArchiveFile opens a container file to append to, creating it if new
+func openArchiveFile(path string) (*archiveFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var cw *gauge.ContainerWriter
+	if info.Size() == 0 {
+		cw, err = gauge.NewContainerWriter(f)
+	} else {
+		cw, err = gauge.AppendContainerWriter(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &archiveFile{path: path, f: f, cw: cw}, nil
+}
+
+// numbered is the nth file of a partition, e.g. rloi-1.avro
+func numbered(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + strconv.Itoa(n) + ext
+}
+
+// source identifies where a station's readings come from, using the alias
+// URL scheme (e.g. rloi, sepa) or the host of a measure URL
+func source(s gauge.Station) string {
+	u, err := url.Parse(s.AliasURL)
+	if err != nil || u.Scheme == "" {
+		return "unknown"
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		return strings.ToLower(u.Host)
+	}
+	return strings.ToLower(u.Scheme)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+func TestArchivePartitions(t *testing.T) {
+	dir := t.TempDir()
+	day, _ := time.Parse(time.RFC3339, "2020-02-15T10:30:00Z")
+	snaps := []*gauge.Snapshot{
+		{Station: gauge.Station{AliasURL: "rloi://1234"}, ProcessedTime: day},
+		{Station: gauge.Station{AliasURL: "sepa://5678"}, ProcessedTime: day},
+		{Station: gauge.Station{AliasURL: "http://environment.data.gov.uk/flood-monitoring/id/measures/1"}, ProcessedTime: day},
+		{Station: gauge.Station{AliasURL: "rloi://1234"}, ProcessedTime: day.Add(24 * time.Hour)},
+	}
+
+	// write the first snapshot, then re-open to check files are appended
+	a := newArchiver(dir)
+	if _, err := a.Write(snaps[0]); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+	a = newArchiver(dir)
+	for _, s := range snaps {
+		if _, err := a.Write(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Close()
+
+	expected := map[string]int{
+		"2020-02-15/rloi.avro":                    2,
+		"2020-02-15/sepa.avro":                    1,
+		"2020-02-15/environment.data.gov.uk.avro": 1,
+		"2020-02-16/rloi.avro":                    1,
+	}
+	for name, n := range expected {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cr, err := gauge.NewContainerReader(f)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		count := 0
+		for {
+			if _, err := cr.Next(); err != nil {
+				break
+			}
+			count++
+		}
+		f.Close()
+		if count != n {
+			t.Error("expected", n, "snapshots in", name, "but got", count)
+		}
+	}
+}
+
+func TestArchiveEarlierSchema(t *testing.T) {
+	dir := t.TempDir()
+	day, _ := time.Parse(time.RFC3339, "2020-02-15T10:30:00Z")
+
+	// a partition file written with a schema from before a field was added
+	var earlier bytes.Buffer
+	writeLong := func(n int64) {
+		b := make([]byte, binary.MaxVarintLen64)
+		earlier.Write(b[:binary.PutVarint(b, n)])
+	}
+	writeString := func(s string) {
+		writeLong(int64(len(s)))
+		earlier.WriteString(s)
+	}
+	earlier.WriteString("Obj\x01")
+	writeLong(1)
+	writeString("avro.schema")
+	writeString(`{"type":"record","name":"Snapshot","fields":[{"name":"correlation_id","type":"string"}]}`)
+	writeLong(0)
+	earlier.Write(make([]byte, 16))
+	path := filepath.Join(dir, "2020-02-15", "rloi.avro")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, earlier.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newArchiver(dir)
+	for _, id := range []string{"a", "b"} {
+		written, err := a.Write(&gauge.Snapshot{
+			Station:       gauge.Station{AliasURL: "rloi://1234"},
+			ProcessedTime: day,
+			CorrelationID: id,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(written) != "rloi-1.avro" {
+			t.Error("expected a new partition file, got", written)
+		}
+	}
+	a.Close()
+
+	if b, _ := ioutil.ReadFile(path); !bytes.Equal(b, earlier.Bytes()) {
+		t.Error("earlier schema file changed")
+	}
+	f, err := os.Open(filepath.Join(dir, "2020-02-15", "rloi-1.avro"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cr, err := gauge.NewContainerReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		s, err := cr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.CorrelationID != id {
+			t.Error("expected", id, "but got", s.CorrelationID)
+		}
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Error("expected EOF, got", err)
+	}
+}
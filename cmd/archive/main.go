@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/queue"
+	"github.com/robtuley/report"
+)
+
+// Responds to environment variables:
+//   PROJECT_ID (no default, blank for local file queue)
+//   PUBSUB_TOPIC (no default)
+//   QUEUE_DIR (no default, subscribe to a local file queue instead of pubsub)
+//   ARCHIVE_DIR (no default, directory to write AVRO container files)
+func main() {
+	cfg := config{
+		ProjectID:  os.Getenv("PROJECT_ID"),
+		TopicName:  os.Getenv("PUBSUB_TOPIC"),
+		QueueDir:   os.Getenv("QUEUE_DIR"),
+		ArchiveDir: os.Getenv("ARCHIVE_DIR"),
+	}
+
+	d := daemon.New("archive")
+	d.Run(context.Background(), cfg.run)
+	d.CloseAfter(24 * time.Hour)
+	d.Wait()
+	if err := d.Err(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+type config struct {
+	ProjectID  string
+	TopicName  string
+	QueueDir   string
+	ArchiveDir string
+	Topic      *queue.Topic
+}
+
+func (cfg config) run(ctx context.Context, d *daemon.Supervisor) error {
+	if cfg.ArchiveDir == "" {
+		return errors.New("ARCHIVE_DIR is required")
+	}
+
+	// connect to the queue unless already provided
+	topic := cfg.Topic
+	if topic == nil {
+		var span report.Span
+		topic, span = queue.Open(ctx, cfg.ProjectID, cfg.QueueDir, cfg.TopicName)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
+		}
+		defer topic.Stop()
+	}
+
+	a := newArchiver(cfg.ArchiveDir)
+	defer a.Close()
+
+	// a named consumer group so no snapshots are missed between restarts
+	return topic.Subscribe(ctx, "archive", func(ctx context.Context, err error, s *gauge.Snapshot) error {
+		if err != nil {
+			d.Logger.Action("snapshot.corrupted", report.Data{
+				"error": err.Error(),
+			})
+			return nil // error with decoding so do not retry delivery
+		}
+
+		span := report.StartSpan("snapshot.archived").Field("station", s.Station.AliasURL)
+		path, err := a.Write(s)
+		d.Trace(span.Field("path", path).End(err))
+		return err
+	})
+}
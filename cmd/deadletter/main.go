@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/queue"
+)
+
+// drainIdleTimeout is how long to wait for more dead-letters on a topic
+// before assuming it has been drained
+const drainIdleTimeout = 10 * time.Second
+
+// Inspects snapshots rejected by the store, and optionally requeues them.
+//
+// Responds to environment variables:
+//   DEADLETTER_FILE (no default, local file of dead-letters to inspect)
+//   DEADLETTER_TOPIC (no default, first drain this topic into the file)
+//   PROJECT_ID (no default, blank for local file queues)
+//   QUEUE_DIR (no default, use local file queues instead of pubsub)
+//   PUBSUB_TOPIC (no default, blank to only inspect, otherwise requeue to it)
+//   REASON (no default, only requeue dead-letters with this in the reason)
+func main() {
+	if err := run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	path := os.Getenv("DEADLETTER_FILE")
+	if path == "" {
+		return errors.New("DEADLETTER_FILE is required")
+	}
+	projectID := os.Getenv("PROJECT_ID")
+	queueDir := os.Getenv("QUEUE_DIR")
+	reason := os.Getenv("REASON")
+
+	file, span := queue.NewDeadLetterFile(path)
+	if err := span.Err(); err != nil {
+		return err
+	}
+
+	// move any dead-letters on the topic into the local file, since
+	// reading them from the topic removes them
+	if topicName := os.Getenv("DEADLETTER_TOPIC"); topicName != "" {
+		n, err := drain(ctx, projectID, queueDir, topicName, file)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Drained %d dead-letters from %s\n", n, topicName)
+	}
+
+	dls, err := file.ReadAll()
+	if err != nil {
+		return err
+	}
+	for _, dl := range dls {
+		fmt.Println(describe(dl))
+	}
+	fmt.Printf("%d dead-letters in %s\n", len(dls), path)
+
+	topicName := os.Getenv("PUBSUB_TOPIC")
+	if topicName == "" {
+		return nil
+	}
+
+	topic, span := queue.Open(ctx, projectID, queueDir, topicName)
+	if err := span.Err(); err != nil {
+		return err
+	}
+	defer topic.Stop()
+
+	// requeue matching dead-letters, keeping the rest in the file
+	var remaining []queue.DeadLetter
+	n := 0
+	for _, dl := range dls {
+		if !strings.Contains(dl.Reason, reason) {
+			remaining = append(remaining, dl)
+			continue
+		}
+		if err := topic.Requeue(ctx, dl).Err(); err != nil {
+			fmt.Println("Requeue failed:", err)
+			remaining = append(remaining, dl)
+			continue
+		}
+		n++
+	}
+	fmt.Printf("Requeued %d dead-letters to %s\n", n, topicName)
+	return file.Replace(remaining)
+}
+
+// drain appends all dead-letters from a topic to the file
+func drain(ctx context.Context, projectID string, queueDir string, topicName string, file *queue.DeadLetterFile) (int, error) {
+	topic, span := queue.OpenDeadLetterTopic(ctx, projectID, queueDir, topicName)
+	if err := span.Err(); err != nil {
+		return 0, err
+	}
+	defer topic.Stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	receivedC := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-receivedC:
+			case <-time.After(drainIdleTimeout):
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	n := 0
+	err := topic.Subscribe(ctx, queue.DeadLetterConsumerGroup, func(ctx context.Context, dl queue.DeadLetter) error {
+		if err := file.Reject(ctx, dl); err != nil {
+			return err
+		}
+		n++
+		select {
+		case receivedC <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	return n, err
+}
+
+// describe summarises a dead-letter on a single line
+func describe(dl queue.DeadLetter) string {
+	summary := dl.RejectedTime.Format(time.RFC3339) + " " + dl.Reason
+	s, err := dl.Snapshot()
+	if err != nil {
+		return fmt.Sprintf("%s: undecodable %d bytes (%s)", summary, len(dl.Data), err)
+	}
+	return fmt.Sprintf("%s: %s %s with %d readings", summary, s.Station.DataURL, s.Station.Name, len(s.Readings))
+}
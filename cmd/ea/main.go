@@ -14,10 +14,14 @@ import (
 // Responds to environment variables:
 //   PROJECT_ID (no default, blank skips publish)
 //   PUBSUB_TOPIC (no default)
+//   QUEUE_DIR (no default, publish to a local file queue instead of pubsub)
+//   SPOOL_DIR (no default, keep snapshots that fail to publish to replay later)
 func main() {
 	cfg := config{
 		ProjectID:                os.Getenv("PROJECT_ID"),
 		TopicName:                os.Getenv("PUBSUB_TOPIC"),
+		QueueDir:                 os.Getenv("QUEUE_DIR"),
+		SpoolDir:                 os.Getenv("SPOOL_DIR"),
 		RefreshPeriodInSeconds:   15 * 60,
 		MaxPublishPerSecond:      30,
 		ExitAfterXConsecutiveErr: 3,
@@ -36,6 +40,8 @@ func main() {
 type config struct {
 	ProjectID                string
 	TopicName                string
+	QueueDir                 string
+	SpoolDir                 string
 	RefreshPeriodInSeconds   int
 	MaxPublishPerSecond      int
 	ExitAfterXConsecutiveErr int
@@ -61,12 +67,19 @@ updateLoop:
 			}
 
 			// open connection to pubsub
-			topic, tSpan := queue.New(ctx, cfg.ProjectID, cfg.TopicName)
+			topic, tSpan := queue.Open(ctx, cfg.ProjectID, cfg.QueueDir, cfg.TopicName)
 			d.Trace(rSpan.FollowedBy(tSpan))
 			if err := tSpan.Err(); err != nil {
 				return err
 			}
 			defer topic.Stop()
+			if cfg.SpoolDir != "" {
+				sSpan := topic.SpoolTo(cfg.SpoolDir)
+				d.Trace(sSpan)
+				if err := sSpan.Err(); err != nil {
+					return err
+				}
+			}
 
 			// ticker to spread readings publish over the full refresh period
 			every := cfg.durationBetweenPublish(len(readings))
@@ -18,6 +18,7 @@ const maxPublishPerSecond = 20
 //   DATE (defaults to yesterday)
 //   PROJECT_ID (no default, blank for validation mode)
 //   PUBSUB_TOPIC (no default, blank for validation mode)
+//   QUEUE_DIR (no default, publish to a local file queue instead of pubsub)
 func main() {
 	d := daemon.New("eaday")
 	d.Run(context.Background(), run)
@@ -44,7 +45,8 @@ func run(ctx context.Context, d *daemon.Supervisor) error {
 	}
 	projectID := os.Getenv("PROJECT_ID")
 	topicName := os.Getenv("PUBSUB_TOPIC")
-	isDryRun := projectID == ""
+	queueDir := os.Getenv("QUEUE_DIR")
+	isDryRun := projectID == "" && queueDir == ""
 
 	// discover EA gauging stations
 	stations, dSpan := ea.Discover(ctx)
@@ -72,7 +74,7 @@ func run(ctx context.Context, d *daemon.Supervisor) error {
 	}
 
 	// open connection to pubsub
-	topic, cSpan := queue.New(ctx, projectID, topicName)
+	topic, cSpan := queue.Open(ctx, projectID, queueDir, topicName)
 	d.Trace(dSpan.FollowedBy(rSpan).FollowedBy(cSpan))
 	if err := cSpan.Err(); err != nil {
 		return err
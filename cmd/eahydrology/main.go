@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers"
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/eahydrology"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/queue"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// maxDaysPerRequest keeps a request for 15 minute readings within the
+// API page size
+const maxDaysPerRequest = 31
+
+// Responds to environment variables:
+//   FROM (defaults to 30 days ago, first date to backfill as yyyy-mm-dd)
+//   TO (defaults to yesterday, last date to backfill as yyyy-mm-dd)
+//   STATIONS (no default, comma separated URLs, blank for calibrated stations)
+//   PROJECT_ID (no default, blank for validation mode)
+//   PUBSUB_TOPIC (no default, blank for validation mode)
+//   QUEUE_DIR (no default, publish to a local file queue instead of pubsub)
+//   CONTENT_DIR (no default, blank for the content compiled into the binary)
+func main() {
+	d := daemon.New("eahydrology")
+	d.Run(context.Background(), run)
+	d.CloseAfter(12 * time.Hour)
+	d.Wait()
+	if err := d.Err(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, d *daemon.Supervisor) error {
+	// parse env vars
+	yesterday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	from, err := parseDate(os.Getenv("FROM"), yesterday.AddDate(0, 0, -29))
+	if err != nil {
+		return err
+	}
+	to, err := parseDate(os.Getenv("TO"), yesterday)
+	if err != nil {
+		return err
+	}
+	if to.Before(from) {
+		return errors.New("TO is before FROM")
+	}
+	projectID := os.Getenv("PROJECT_ID")
+	topicName := os.Getenv("PUBSUB_TOPIC")
+	queueDir := os.Getenv("QUEUE_DIR")
+	isDryRun := projectID == "" && queueDir == ""
+	content, err := rainchasers.LoadContent(os.Getenv("CONTENT_DIR"), d.Logger)
+	if err != nil {
+		return err
+	}
+
+	// discover hydrology stations to backfill
+	stations, dSpan := eahydrology.Discover(ctx)
+	if err := dSpan.Err(); err != nil {
+		d.Trace(dSpan)
+		return err
+	}
+	selected := selectStations(stations, wantedURLs(os.Getenv("STATIONS"), content.Content().Calibrations))
+
+	// if dry run shorten the run
+	if isDryRun && len(selected) > 3 {
+		selected = selected[:3]
+	}
+
+	// open connection to pubsub
+	topic, cSpan := queue.Open(ctx, projectID, queueDir, topicName)
+	d.Trace(dSpan.FollowedBy(cSpan))
+	if err := cSpan.Err(); err != nil {
+		return err
+	}
+	defer topic.Stop()
+
+	// publish readings for each station a month at a time
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for _, s := range selected {
+		for start := from; !start.After(to); start = start.AddDate(0, 0, maxDaysPerRequest) {
+			end := start.AddDate(0, 0, maxDaysPerRequest-1)
+			if end.After(to) {
+				end = to
+			}
+
+			readings, rSpan := eahydrology.Readings(ctx, s.DataURL, start, end)
+			if err := rSpan.Err(); err != nil {
+				d.Trace(rSpan)
+				return err
+			}
+			if len(readings) > 0 {
+				span := topic.Publish(ctx, &gauge.Snapshot{
+					Station:       s,
+					Readings:      readings,
+					CorrelationID: rSpan.TraceID(),
+					CausationID:   rSpan.SpanID(),
+				})
+				d.Trace(rSpan.FollowedBy(span))
+				if err := span.Err(); err != nil {
+					return err
+				}
+			} else {
+				d.Trace(rSpan)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				// exit early on shutdown
+				return nil
+			}
+		}
+	}
+
+	// all done so shutdown
+	d.Close()
+	return nil
+}
+
+func parseDate(s string, defaultDate time.Time) (time.Time, error) {
+	if s == "" {
+		return defaultDate, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// wantedURLs are the requested station URLs, or all calibrated URLs if
+// none are requested
+func wantedURLs(requested string, calibrations map[string][]river.Calibration) map[string]bool {
+	urls := make(map[string]bool)
+	for _, u := range strings.Split(requested, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls[u] = true
+		}
+	}
+	if len(urls) > 0 {
+		return urls
+	}
+
+	for _, cals := range calibrations {
+		for _, c := range cals {
+			for _, url := range c.RouteURLs() {
+				urls[url] = true
+			}
+		}
+	}
+	return urls
+}
+
+// selectStations finds the stations with a data, alias or human URL that
+// is wanted, in a consistent order
+func selectStations(stations map[string]gauge.Station, urls map[string]bool) []gauge.Station {
+	var selected []gauge.Station
+	for _, s := range stations {
+		if urls[s.DataURL] || urls[s.AliasURL] || urls[s.HumanURL] {
+			selected = append(selected, s)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].DataURL < selected[j].DataURL
+	})
+	return selected
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/robtuley/rainchasers"
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+func TestSelectStations(t *testing.T) {
+	stations := map[string]gauge.Station{
+		"https://example.com/measures/b": {DataURL: "https://example.com/measures/b", AliasURL: "rloi://2"},
+		"https://example.com/measures/a": {DataURL: "https://example.com/measures/a", AliasURL: "rloi://1"},
+		"https://example.com/measures/c": {DataURL: "https://example.com/measures/c", AliasURL: "rloi://3"},
+	}
+
+	selected := selectStations(stations, wantedURLs("rloi://2, https://example.com/measures/a", rainchasers.Calibrations))
+	if len(selected) != 2 {
+		t.Fatal("expected 2 stations, got", len(selected))
+	}
+	if selected[0].AliasURL != "rloi://1" || selected[1].AliasURL != "rloi://2" {
+		t.Error("stations not selected in order", selected)
+	}
+}
+
+func TestWantedURLsDefaultsToCalibrated(t *testing.T) {
+	if len(wantedURLs("", rainchasers.Calibrations)) == 0 {
+		t.Error("expected calibrated station URLs")
+	}
+}
@@ -16,10 +16,14 @@ import (
 //   PROJECT_ID (no default, blank for validation mode)
 //   PUBSUB_TOPIC (no default, blank for validation mode)
 //   NRW_API_KEY (no default)
+//   QUEUE_DIR (no default, publish to a local file queue instead of pubsub)
+//   SPOOL_DIR (no default, keep snapshots that fail to publish to replay later)
 func main() {
 	cfg := config{
 		ProjectID:                os.Getenv("PROJECT_ID"),
 		TopicName:                os.Getenv("PUBSUB_TOPIC"),
+		QueueDir:                 os.Getenv("QUEUE_DIR"),
+		SpoolDir:                 os.Getenv("SPOOL_DIR"),
 		APIKey:                   os.Getenv("NRW_API_KEY"),
 		RefreshPeriodInSeconds:   15 * 60,
 		MaxPublishPerSecond:      30,
@@ -39,6 +43,8 @@ func main() {
 type config struct {
 	ProjectID                string
 	TopicName                string
+	QueueDir                 string
+	SpoolDir                 string
 	APIKey                   string
 	RefreshPeriodInSeconds   int
 	MaxPublishPerSecond      int
@@ -47,12 +53,19 @@ type config struct {
 
 func (cfg config) run(ctx context.Context, d *daemon.Supervisor) error {
 	// open connection to pubsub
-	topic, span := queue.New(ctx, cfg.ProjectID, cfg.TopicName)
+	topic, span := queue.Open(ctx, cfg.ProjectID, cfg.QueueDir, cfg.TopicName)
 	d.Trace(span)
 	if err := span.Err(); err != nil {
 		return err
 	}
 	defer topic.Stop()
+	if cfg.SpoolDir != "" {
+		span := topic.SpoolTo(cfg.SpoolDir)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
+		}
+	}
 
 	nConsecutiveErr := 0
 pollLoop:
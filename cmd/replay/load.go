@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/ea"
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+// filter selects the stations and readings to replay
+type filter struct {
+	From     time.Time
+	To       time.Time
+	Stations map[string]bool
+}
+
+// Apply removes readings outside of the date range, and returns false
+// if the snapshot should not be replayed at all
+func (f filter) Apply(s *gauge.Snapshot) bool {
+	if len(f.Stations) > 0 && !f.Stations[s.Station.DataURL] && !f.Stations[s.Station.AliasURL] {
+		return false
+	}
+
+	readings := s.Readings[:0]
+	for _, r := range s.Readings {
+		if !f.From.IsZero() && r.EventTime.Before(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && !r.EventTime.Before(f.To) {
+			continue
+		}
+		readings = append(readings, r)
+	}
+	s.Readings = readings
+	return len(readings) > 0
+}
+
+// replayTime is when a snapshot originally happened, the time of its
+// most recent reading
+func replayTime(s *gauge.Snapshot) time.Time {
+	var latest time.Time
+	for _, r := range s.Readings {
+		if r.EventTime.After(latest) {
+			latest = r.EventTime
+		}
+	}
+	return latest
+}
+
+// sortByReplayTime orders snapshots as they originally happened
+func sortByReplayTime(snaps []*gauge.Snapshot) {
+	sort.SliceStable(snaps, func(i, j int) bool {
+		return replayTime(snaps[i]).Before(replayTime(snaps[j]))
+	})
+}
+
+// isCSV identifies files in the EA daily archive CSV format, rather than
+// AVRO container files
+func isCSV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// loadContainer reads all the snapshots in an AVRO container file
+func loadContainer(path string, f filter) ([]*gauge.Snapshot, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	cr, err := gauge.NewContainerReader(fh)
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []*gauge.Snapshot
+	for {
+		s, err := cr.Next()
+		if err == io.EOF {
+			return snaps, nil
+		}
+		if err != nil {
+			return snaps, err
+		}
+		if f.Apply(s) {
+			snaps = append(snaps, s)
+		}
+	}
+}
+
+// loadCSV reads an EA daily archive CSV, creating a snapshot for each
+// reading of a known station as if it had been polled in real time
+func loadCSV(path string, stations map[string]gauge.Station, f filter) ([]*gauge.Snapshot, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	readings, err := ea.ParseDay(fh)
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []*gauge.Snapshot
+	for id, rs := range readings {
+		station, ok := stations[id]
+		if !ok {
+			continue
+		}
+		for _, r := range rs {
+			s := &gauge.Snapshot{
+				Station:  station,
+				Readings: []gauge.Reading{r},
+			}
+			if f.Apply(s) {
+				snaps = append(snaps, s)
+			}
+		}
+	}
+	return snaps, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+func TestLoadContainer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rloi.avro")
+	day, _ := time.Parse(time.RFC3339, "2020-02-15T10:30:00Z")
+
+	fh, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw, err := gauge.NewContainerWriter(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*gauge.Snapshot{
+		{
+			Station:       gauge.Station{AliasURL: "rloi://1"},
+			Readings:      []gauge.Reading{{EventTime: day.Add(time.Hour)}},
+			CorrelationID: "later",
+		},
+		{
+			Station:       gauge.Station{AliasURL: "rloi://1"},
+			Readings:      []gauge.Reading{{EventTime: day}, {EventTime: day.AddDate(0, 0, -1)}},
+			CorrelationID: "earlier",
+		},
+		{
+			Station:  gauge.Station{AliasURL: "rloi://2"},
+			Readings: []gauge.Reading{{EventTime: day}},
+		},
+	} {
+		cw.Write(s)
+	}
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	fh.Close()
+
+	f, err := parseFilter("2020-02-15", "2020-02-15", "rloi://1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snaps, err := loadContainer(path, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortByReplayTime(snaps)
+
+	if len(snaps) != 2 {
+		t.Fatal("expected 2 snapshots for station, got", len(snaps))
+	}
+	if snaps[0].CorrelationID != "earlier" || snaps[1].CorrelationID != "later" {
+		t.Error("snapshots not in replay order", snaps[0].CorrelationID, snaps[1].CorrelationID)
+	}
+	if len(snaps[0].Readings) != 1 || !snaps[0].Readings[0].EventTime.Equal(day) {
+		t.Error("readings outside date range not removed", snaps[0].Readings)
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	url := "http://environment.data.gov.uk/flood-monitoring/id/measures/2077-level-stage-i-15_min-m"
+	stations := map[string]gauge.Station{
+		url: {DataURL: url, AliasURL: "rloi://2077"},
+	}
+
+	path := filepath.Join(t.TempDir(), "readings-2020-02-15.csv")
+	csv := "dateTime,measure,value\n" +
+		"2020-02-15T00:00:00Z," + url + ",0.300\n" +
+		"2020-02-15T00:15:00Z," + url + ",0.306\n" +
+		"2020-02-15T00:30:00Z," + url + ",0.311\n"
+	if err := ioutil.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snaps, err := loadCSV(path, stations, filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 3 {
+		t.Fatal("expected a snapshot per reading, got", len(snaps))
+	}
+	for _, s := range snaps {
+		if s.Station.AliasURL != "rloi://2077" || len(s.Readings) != 1 {
+			t.Fatal("snapshot not for station reading", s)
+		}
+	}
+
+	// unknown stations are skipped
+	snaps, _ = loadCSV(path, nil, filter{})
+	if len(snaps) != 0 {
+		t.Error("expected unknown station to be skipped", len(snaps))
+	}
+}
+
+func TestScheduleAt(t *testing.T) {
+	start := time.Now()
+	first, _ := time.Parse(time.RFC3339, "2020-02-15T10:00:00Z")
+
+	at := scheduleAt(start, first, first.Add(time.Hour), 60)
+	if at.Sub(start) != time.Minute {
+		t.Error("expected an hour to replay in a minute, got", at.Sub(start))
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/ea"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/queue"
+	"github.com/robtuley/report"
+)
+
+// maxPublishPerSecond limits the publish rate when replaying at full speed
+const maxPublishPerSecond = 20
+
+// Responds to environment variables:
+//   REPLAY_FILES (no default, comma separated globs of .avro or EA .csv files)
+//   FROM (no default, first date of readings to replay as yyyy-mm-dd)
+//   TO (no default, last date of readings to replay as yyyy-mm-dd)
+//   STATIONS (no default, comma separated data or alias URLs to replay)
+//   SPEED (defaults to 0 for full speed, or e.g. 60 to replay an hour a minute)
+//   PROJECT_ID (no default, blank for dry run)
+//   PUBSUB_TOPIC (no default)
+//   QUEUE_DIR (no default, publish to a local file queue instead of pubsub)
+func main() {
+	d := daemon.New("replay")
+	d.Run(context.Background(), run)
+	d.CloseAfter(24 * time.Hour)
+	d.Wait()
+	if err := d.Err(); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, d *daemon.Supervisor) error {
+	// parse env vars
+	paths, err := globAll(os.Getenv("REPLAY_FILES"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no REPLAY_FILES found")
+	}
+	f, err := parseFilter(os.Getenv("FROM"), os.Getenv("TO"), os.Getenv("STATIONS"))
+	if err != nil {
+		return err
+	}
+	speed := 0.0
+	if s := os.Getenv("SPEED"); s != "" {
+		speed, err = strconv.ParseFloat(s, 64)
+		if err != nil || speed < 0 {
+			return errors.New("SPEED must be a positive number")
+		}
+	}
+
+	// the EA CSV format only has measure URLs so needs station details
+	var stations map[string]gauge.Station
+	for _, path := range paths {
+		if isCSV(path) {
+			var span report.Span
+			stations, span = ea.Discover(ctx)
+			d.Trace(span)
+			if err := span.Err(); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	// load all snapshots to replay in order
+	var snaps []*gauge.Snapshot
+	for _, path := range paths {
+		span := report.StartSpan("replay.loaded").Field("path", path)
+		var loaded []*gauge.Snapshot
+		if isCSV(path) {
+			loaded, err = loadCSV(path, stations, f)
+		} else {
+			loaded, err = loadContainer(path, f)
+		}
+		d.Trace(span.Field("count_snapshots", len(loaded)).End(err))
+		if err != nil {
+			return err
+		}
+		snaps = append(snaps, loaded...)
+	}
+	sortByReplayTime(snaps)
+
+	// open connection to pubsub
+	topic, span := queue.Open(ctx, os.Getenv("PROJECT_ID"), os.Getenv("QUEUE_DIR"), os.Getenv("PUBSUB_TOPIC"))
+	d.Trace(span)
+	if err := span.Err(); err != nil {
+		return err
+	}
+	defer topic.Stop()
+
+	// publish snapshots, keeping their original correlation ID
+	start := time.Now()
+	minGap := time.Second / maxPublishPerSecond
+	var last time.Time
+	for _, s := range snaps {
+		at := last.Add(minGap)
+		if speed > 0 {
+			at = scheduleAt(start, replayTime(snaps[0]), replayTime(s), speed)
+		}
+		select {
+		case <-time.After(time.Until(at)):
+		case <-ctx.Done():
+			// exit early on shutdown
+			return nil
+		}
+		last = time.Now()
+
+		span := topic.Publish(ctx, s)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
+		}
+	}
+
+	// all done so shutdown
+	d.Close()
+	return nil
+}
+
+// scheduleAt is when to publish a snapshot, compressing the time since the
+// first snapshot by the replay speed
+func scheduleAt(start time.Time, first time.Time, t time.Time, speed float64) time.Time {
+	return start.Add(time.Duration(float64(t.Sub(first)) / speed))
+}
+
+func globAll(patterns string) ([]string, error) {
+	var paths []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func parseFilter(from string, to string, stations string) (filter, error) {
+	f := filter{Stations: make(map[string]bool)}
+
+	var err error
+	if from != "" {
+		f.From, err = time.Parse("2006-01-02", from)
+		if err != nil {
+			return f, errors.New("FROM must be yyyy-mm-dd")
+		}
+	}
+	if to != "" {
+		f.To, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			return f, errors.New("TO must be yyyy-mm-dd")
+		}
+		// include all readings on the last day
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+
+	for _, s := range strings.Split(stations, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			f.Stations[s] = true
+		}
+	}
+	return f, nil
+}
@@ -102,5 +102,27 @@ func csvRecordToSnapshot(r []string) (gauge.Station, error) {
 	}
 	s.Unit = r[17]
 
+	// optional statistics are blank or "---" if unknown
+	if r[4] != "---" {
+		s.CatchmentName = strings.TrimSpace(r[4])
+	}
+	s.Datum = parseStatistic(r[6])
+	s.CatchmentArea = parseStatistic(r[7])
+	s.Lowest = parseStatistic(r[11])
+	s.Low = parseStatistic(r[12])
+	s.Highest = parseStatistic(r[13])
+	s.High = parseStatistic(r[14])
+	s.Mean = parseStatistic(r[16])
+	s.InfoURL = strings.TrimSpace(r[19])
+
 	return s, nil
 }
+
+func parseStatistic(v string) *float32 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 32)
+	if err != nil {
+		return nil
+	}
+	f32 := float32(f)
+	return &f32
+}
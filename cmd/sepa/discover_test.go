@@ -63,3 +63,48 @@ func TestDiscoveringStations(t *testing.T) {
 		t.Error("Too many missing lg", nMissingLg, len(stations))
 	}
 }
+
+func TestCSVRecordStatistics(t *testing.T) {
+	r := []string{"Perth", "Perth", "10048", "NO1160525132", "---", "Tay", "2.08", "4991.0", "August 19", "2017-02-20 12:45:00", "58156010", "0.0", "0.168", "4.928", "3.493", "4.928m @ 17/01/1993 19:30:00", "0.894", "m", "", "http://www.ceh.ac.uk/data/nrfa/data/station.html?15042"}
+
+	s, err := csvRecordToSnapshot(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, v := range map[string]struct {
+		got  *float32
+		want float32
+	}{
+		"datum":          {s.Datum, 2.08},
+		"catchment_area": {s.CatchmentArea, 4991.0},
+		"lowest":         {s.Lowest, 0.0},
+		"low":            {s.Low, 0.168},
+		"high":           {s.High, 3.493},
+		"highest":        {s.Highest, 4.928},
+		"mean":           {s.Mean, 0.894},
+	} {
+		if v.got == nil {
+			t.Error("Missing", name)
+			continue
+		}
+		if *v.got != v.want {
+			t.Error("Bad", name, *v.got, v.want)
+		}
+	}
+	if s.CatchmentName != "" {
+		t.Error("Placeholder catchment name not ignored", s.CatchmentName)
+	}
+	if s.InfoURL != "http://www.ceh.ac.uk/data/nrfa/data/station.html?15042" {
+		t.Error("Bad info URL", s.InfoURL)
+	}
+
+	r[12] = ""
+	s, err = csvRecordToSnapshot(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Low != nil {
+		t.Error("Blank low should be nil", *s.Low)
+	}
+}
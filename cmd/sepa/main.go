@@ -8,15 +8,20 @@ import (
 	"github.com/robtuley/rainchasers/internal/daemon"
 	"github.com/robtuley/rainchasers/internal/gauge"
 	"github.com/robtuley/rainchasers/internal/queue"
+	"github.com/robtuley/report"
 )
 
 // Responds to environment variables:
 //   PROJECT_ID (no default, blank for validation mode)
 //   PUBSUB_TOPIC (no default, blank for validation mode)
+//   QUEUE_DIR (no default, publish to a local file queue instead of pubsub)
+//   SPOOL_DIR (no default, keep snapshots that fail to publish to replay later)
 func main() {
 	cfg := config{
 		ProjectID:                os.Getenv("PROJECT_ID"),
 		TopicName:                os.Getenv("PUBSUB_TOPIC"),
+		QueueDir:                 os.Getenv("QUEUE_DIR"),
+		SpoolDir:                 os.Getenv("SPOOL_DIR"),
 		RefreshPeriodInSeconds:   15 * 60,
 		ExitAfterXConsecutiveErr: 3,
 	}
@@ -35,6 +40,8 @@ func main() {
 type config struct {
 	ProjectID                string
 	TopicName                string
+	QueueDir                 string
+	SpoolDir                 string
 	RefreshPeriodInSeconds   int
 	ExitAfterXConsecutiveErr int
 }
@@ -47,18 +54,33 @@ func (cfg config) run(ctx context.Context, d *daemon.Supervisor) error {
 		return err
 	}
 
+	// add SEPA rainfall stations, but continue with only the
+	// level stations if these are unavailable
+	rainfall, rSpan := discoverRainfall(ctx)
+	dSpan = dSpan.FollowedBy(rSpan)
+	if rSpan.Err() == nil {
+		stations = append(stations, rainfall...)
+	}
+
 	// calculate update rate to refresh on schedule
 	every := cfg.durationBetweenPublish(len(stations))
 	ticker := time.NewTicker(every)
 	defer ticker.Stop()
 
 	// open connection to pubsub
-	topic, qSpan := queue.New(ctx, cfg.ProjectID, cfg.TopicName)
+	topic, qSpan := queue.Open(ctx, cfg.ProjectID, cfg.QueueDir, cfg.TopicName)
 	d.Trace(dSpan.FollowedBy(qSpan))
 	if err := qSpan.Err(); err != nil {
 		return err
 	}
 	defer topic.Stop()
+	if cfg.SpoolDir != "" {
+		sSpan := topic.SpoolTo(cfg.SpoolDir)
+		d.Trace(sSpan)
+		if err := sSpan.Err(); err != nil {
+			return err
+		}
+	}
 
 	// get readings & publish them to pubsub
 	nConsecutiveErr := 0
@@ -67,7 +89,7 @@ updateLoop:
 	for {
 		i := n % len(stations)
 
-		readings, span := getReadings(ctx, stations[i].DataURL)
+		readings, span := readingsFor(ctx, stations[i])
 		if err := span.Err(); err == nil {
 			span = span.FollowedBy(topic.Publish(ctx, &gauge.Snapshot{
 				Station:       stations[i],
@@ -101,6 +123,15 @@ updateLoop:
 	return nil
 }
 
+// readingsFor gets the readings for either a level or rainfall station so
+// both share the same rate limit
+func readingsFor(ctx context.Context, s gauge.Station) ([]gauge.Reading, report.Span) {
+	if s.Type == "rainfall" {
+		return getRainfallReadings(ctx, s.DataURL)
+	}
+	return getReadings(ctx, s.DataURL)
+}
+
 func (cfg config) durationBetweenPublish(total int) time.Duration {
 	ms := cfg.RefreshPeriodInSeconds * 1000 / total
 	min := 1500 // SEPA rate limiter ~ 1 req/per second
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/report"
+)
+
+const rainfallAPI = "https://www2.sepa.org.uk/rainfall/api/"
+
+type rainfallStationJSON struct {
+	Name      string `json:"station_name"`
+	No        string `json:"station_no"`
+	Lat       string `json:"station_latitude"`
+	Lg        string `json:"station_longitude"`
+	Catchment string `json:"catchment_name"`
+}
+
+type rainfallReadingJSON struct {
+	Timestamp string `json:"Timestamp"`
+	Value     string `json:"Value"`
+}
+
+func discoverRainfall(ctx context.Context) ([]gauge.Station, report.Span) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	url := rainfallAPI + "Stations?json=true"
+	span := report.StartSpan("sepa.rainfall.discover").Field("url", url)
+
+	resp, err := daemon.JSON(ctx, url)
+	if err != nil {
+		return nil, span.End(err)
+	}
+	defer resp.Body.Close()
+
+	stations, err := parseRainfallStations(resp.Body)
+	if err != nil {
+		return stations, span.End(err)
+	}
+
+	span = span.Field("stations_count", len(stations))
+	return stations, span.End()
+}
+
+// [{"station_name":"Aberdeen","station_no":"234243","station_latitude":"57.14","station_longitude":"-2.11","catchment_name":"Dee (Grampian)"}]
+func parseRainfallStations(body io.Reader) ([]gauge.Station, error) {
+	var list []rainfallStationJSON
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	var stations []gauge.Station
+	for _, r := range list {
+		if r.No == "" {
+			continue
+		}
+
+		s := gauge.Station{
+			DataURL:   rainfallAPI + "Hourly/" + r.No,
+			AliasURL:  "sepa-rainfall://" + r.No,
+			HumanURL:  "https://www2.sepa.org.uk/rainfall/data/index/" + r.No,
+			Name:      r.Name,
+			RiverName: r.Catchment,
+			Type:      "rainfall",
+			Unit:      "mm",
+		}
+
+		// ignore location errors as some stations do not have
+		// a valid location in the provided dataset
+		lat, err := strconv.ParseFloat(strings.TrimSpace(r.Lat), 32)
+		if err == nil {
+			s.Lat = float32(lat)
+		}
+		lg, err := strconv.ParseFloat(strings.TrimSpace(r.Lg), 32)
+		if err == nil {
+			s.Lg = float32(lg)
+		}
+
+		stations = append(stations, s)
+	}
+	return stations, nil
+}
+
+func getRainfallReadings(ctx context.Context, dataURL string) ([]gauge.Reading, report.Span) {
+	span := report.StartSpan("sepa.rainfall.recent")
+	span = span.Field("url", dataURL)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	resp, err := daemon.JSON(ctx, dataURL)
+	if err != nil {
+		return nil, span.End(err)
+	}
+	defer resp.Body.Close()
+
+	readings, err := parseRainfallReadings(resp.Body)
+	if err != nil {
+		return readings, span.End(err)
+	}
+
+	span = span.Field("readings_count", len(readings))
+	return readings, span.End()
+}
+
+// [{"Timestamp":"2020-02-15 10:00:00","Value":"0.4"}]
+func parseRainfallReadings(body io.Reader) ([]gauge.Reading, error) {
+	var list []rainfallReadingJSON
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	var readings []gauge.Reading
+	for _, r := range list {
+		t, err := time.Parse("2006-01-02 15:04:05", r.Timestamp)
+		if err != nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(r.Value), 32)
+		if err != nil {
+			continue
+		}
+		readings = append(readings, gauge.Reading{
+			EventTime: t,
+			Value:     float32(v),
+		})
+	}
+	return readings, nil
+}
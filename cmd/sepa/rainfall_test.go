@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRainfallStations(t *testing.T) {
+	const response = `[
+		{"station_name":"Aberdeen","station_no":"234243","station_latitude":"57.14","station_longitude":"-2.11","catchment_name":"Dee (Grampian)"},
+		{"station_name":"Unknown","station_no":"","station_latitude":"","station_longitude":""},
+		{"station_name":"Achnagart","station_no":"115308","station_latitude":"","station_longitude":"","catchment_name":"Shiel"}
+	]`
+
+	stations, err := parseRainfallStations(strings.NewReader(response))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != 2 {
+		t.Fatal("expected stations without a number skipped", len(stations))
+	}
+
+	s := stations[0]
+	if s.Type != "rainfall" || s.Unit != "mm" {
+		t.Error("not a rainfall station", s)
+	}
+	if s.AliasURL != "sepa-rainfall://234243" || !strings.HasSuffix(s.DataURL, "/Hourly/234243") {
+		t.Error("station URLs not mapped", s)
+	}
+	if s.Name != "Aberdeen" || s.RiverName != "Dee (Grampian)" || s.Lat < 57 || s.Lg > -2 {
+		t.Error("station not parsed", s)
+	}
+	if stations[1].Lat != 0 || stations[1].Lg != 0 {
+		t.Error("missing location not ignored", stations[1])
+	}
+}
+
+func TestParseRainfallReadings(t *testing.T) {
+	const response = `[
+		{"Timestamp":"2020-02-15 09:00:00","Value":"0.0"},
+		{"Timestamp":"2020-02-15 10:00:00","Value":"1.4"},
+		{"Timestamp":"2020-02-15 11:00:00","Value":"---"}
+	]`
+
+	readings, err := parseRainfallReadings(strings.NewReader(response))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 2 {
+		t.Fatal("expected invalid values skipped", readings)
+	}
+	if readings[1].Value != 1.4 || readings[1].EventTime.Hour() != 10 {
+		t.Error("reading not parsed", readings[1])
+	}
+}
@@ -31,28 +31,8 @@ func (aw *AlgoliaWriter) StoreRecord(ctx context.Context, record *Record) report
 	ctx, cancel := context.WithTimeout(ctx, aw.Timeout)
 	defer cancel()
 
-	uuid := record.Section.UUID
-	s := record.Section
-	l := record.Level
-
-	span := report.StartSpan("algolia.store").Field("uuid", s.UUID)
-	object := algoliasearch.Object{
-		"objectID":      uuid,
-		"slug":          s.Slug,
-		"section":       s.SectionName,
-		"river":         s.RiverName,
-		"grade":         s.Grade.Human,
-		"grade_numeric": s.Grade.Average,
-		"desc":          s.Description,
-		"km":            s.KM,
-		"_geoloc": map[string]float32{
-			"lat": s.Putin.Lat,
-			"lng": s.Putin.Lng,
-		},
-		"level_label":     l.Label,
-		"level_reason":    l.Reason,
-		"level_timestamp": l.EventTime,
-	}
+	span := report.StartSpan("algolia.store").Field("uuid", record.Section.UUID)
+	object := algoliasearch.Object(recordObject(record))
 	_, err := aw.RiverIndex.UpdateObject(object)
 	if err != nil {
 		return span.End(err)
@@ -67,18 +47,7 @@ func (aw *AlgoliaWriter) StoreStation(ctx context.Context, station gauge.Station
 	defer cancel()
 
 	span := report.StartSpan("algolia.store").Field("data_url", station.DataURL)
-	object := algoliasearch.Object{
-		"objectID":  station.DataURL,
-		"alias_url": station.AliasURL,
-		"human_url": station.HumanURL,
-		"name":      station.Name,
-		"river":     station.RiverName,
-		"type":      station.Type,
-		"_geoloc": map[string]float32{
-			"lat": station.Lat,
-			"lng": station.Lg,
-		},
-	}
+	object := algoliasearch.Object(stationObject(station))
 	_, err := aw.StationIndex.UpdateObject(object)
 	if err != nil {
 		return span.End(err)
@@ -86,3 +55,38 @@ func (aw *AlgoliaWriter) StoreStation(ctx context.Context, station gauge.Station
 
 	return span.End()
 }
+
+// ListRecords retrieves the uuid of every river record in algolia
+func (aw *AlgoliaWriter) ListRecords(ctx context.Context) ([]string, report.Span) {
+	span := report.StartSpan("algolia.list")
+
+	var uuids []string
+	it, err := aw.RiverIndex.BrowseAll(algoliasearch.Map{
+		"attributesToRetrieve": []string{"objectID"},
+	})
+	for err == nil {
+		var hit algoliasearch.Map
+		hit, err = it.Next()
+		if err != nil {
+			break
+		}
+		if uuid, ok := hit["objectID"].(string); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+	if err != algoliasearch.NoMoreHitsErr {
+		return nil, span.End(err)
+	}
+	return uuids, span.Field("count", len(uuids)).End()
+}
+
+// DeleteRecord removes a river record from algolia
+func (aw *AlgoliaWriter) DeleteRecord(ctx context.Context, uuid string) report.Span {
+	span := report.StartSpan("algolia.delete").Field("uuid", uuid)
+	_, err := aw.RiverIndex.DeleteObject(uuid)
+	if err != nil {
+		return span.End(err)
+	}
+
+	return span.End()
+}
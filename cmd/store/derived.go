@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// findDerivedInput finds a derived gauge the station is an input to,
+// returning a calibration without thresholds to store the input readings
+func findDerivedInput(calibrations []river.Calibration, station gauge.Station) (cal river.Calibration, exists bool) {
+	for _, c := range calibrations {
+		if !c.IsDerived() {
+			continue
+		}
+		for _, url := range c.Derived.Inputs {
+			if url == station.DataURL || url == station.AliasURL || url == station.HumanURL {
+				return river.Calibration{
+					URL:         url,
+					Description: "Input to " + c.URL,
+					Type:        station.Type,
+					Minimum:     make(map[string]float32),
+				}, true
+			}
+		}
+	}
+	return cal, false
+}
+
+// deriveMeasures updates the measure of each derived gauge from the current
+// readings of its inputs, once there are readings from every input
+func deriveMeasures(calibrations []river.Calibration, measures []Measure) []Measure {
+nextCalibration:
+	for _, cal := range calibrations {
+		if !cal.IsDerived() {
+			continue
+		}
+
+		gaugeType := cal.Type
+		if gaugeType == "" {
+			gaugeType = river.DefaultCalibrationType
+		}
+
+		var inputs [][]gauge.Reading
+		var names []string
+		var processed time.Time
+		for _, url := range cal.Derived.Inputs {
+			m, ok := findInputMeasure(measures, url, gaugeType)
+			if !ok || len(m.Readings) == 0 {
+				continue nextCalibration
+			}
+			inputs = append(inputs, m.Readings)
+			names = append(names, m.Station.Name)
+			if m.ProcessedTime.After(processed) {
+				processed = m.ProcessedTime
+			}
+		}
+
+		derived := Measure{
+			Station: gauge.Station{
+				DataURL:  cal.URL,
+				AliasURL: cal.URL,
+				Name:     strings.Join(names, " "+cal.Derived.Symbol()+" "),
+				Type:     gaugeType,
+				Unit:     cal.Unit,
+			},
+			Calibration:   cal,
+			Readings:      cal.Derived.Combine(inputs),
+			ProcessedTime: processed,
+		}
+
+		replaced := false
+		for i := range measures {
+			if measures[i].Station.DataURL == cal.URL {
+				measures[i] = derived
+				replaced = true
+			}
+		}
+		if !replaced {
+			measures = append(measures, derived)
+		}
+	}
+	return measures
+}
+
+// findInputMeasure finds the measure of an input station, preferring the
+// same type as the derived gauge as an alias is shared by all measures at
+// a station
+func findInputMeasure(measures []Measure, url string, gaugeType string) (Measure, bool) {
+	var found Measure
+	exists := false
+	for _, m := range measures {
+		if m.Calibration.IsDerived() {
+			continue
+		}
+		if url != m.Station.DataURL && url != m.Station.AliasURL && url != m.Station.HumanURL {
+			continue
+		}
+		if !exists || m.Station.Type == gaugeType {
+			found = m
+			exists = true
+		}
+	}
+	return found, exists
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func TestDerivedMeasures(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	calibrations := []river.Calibration{{
+		URL:     "derived://confluence",
+		Type:    "flow",
+		Derived: river.Derivation{Op: river.Sum, Inputs: []string{"rloi://1", "rloi://2"}},
+		Minimum: map[string]float32{river.Low.String(): 10, river.High.String(): 20},
+	}}
+
+	tributary := func(url string, name string, value float32) Measure {
+		station := gauge.Station{DataURL: url + "/flow", AliasURL: url, Name: name, Type: "flow"}
+		cal, exists := findCalibrationForStation(calibrations, station)
+		if !exists {
+			t.Fatal("Derived input not found for", url)
+		}
+		return Measure{
+			Station:     station,
+			Calibration: cal,
+			Readings:    []gauge.Reading{{EventTime: now, Value: value}},
+		}
+	}
+
+	measures := deriveMeasures(calibrations, []Measure{tributary("rloi://1", "Wye", 8)})
+	if len(measures) != 1 {
+		t.Fatal("Derived before all inputs read", measures)
+	}
+
+	measures = deriveMeasures(calibrations, append(measures, tributary("rloi://2", "Lugg", 5)))
+	if len(measures) != 3 {
+		t.Fatal("Derived measure not added", measures)
+	}
+	l, _ := resolveLevel("", calibrations, measures, now)
+	if l.Label != river.Low.String() || l.Reason != "13.00 at Wye + Lugg" {
+		t.Error("Unexpected derived level", l)
+	}
+
+	// an update to an input replaces the derived measure
+	measures[0].Readings = []gauge.Reading{{EventTime: now.Add(time.Minute), Value: 18}}
+	measures[1].Readings = append([]gauge.Reading{{EventTime: now.Add(time.Minute), Value: 6}}, measures[1].Readings...)
+	measures = deriveMeasures(calibrations, measures)
+	if len(measures) != 3 {
+		t.Fatal("Derived measure duplicated", measures)
+	}
+	if l, _ := resolveLevel("", calibrations, measures, now); l.Label != river.High.String() {
+		t.Error("Derived level not updated", l)
+	}
+
+	// the derived measure is matched to its calibration when reloaded
+	if _, exists := findCalibrationForStation(calibrations, measures[2].Station); !exists {
+		t.Error("Stored derived measure not matched to calibration")
+	}
+}
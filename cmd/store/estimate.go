@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// minEstimateReadings is the number of readings needed to estimate a typical
+// range from recent readings, a day of 15 minute readings
+const minEstimateReadings = 96
+
+// estimate approximates the level of the latest reading for a gauge without
+// thresholds, using the typical range of the station if known or the range
+// of recent readings otherwise
+func (m Measure) estimate() (label string, reason string) {
+	r := m.Readings[0]
+	v := format(r.Value)
+
+	low, high, basis := m.typicalRange()
+	if basis == "" {
+		return river.Unknown.String(),
+			"Not enough readings from " + m.Station.Name + " to estimate a level"
+	}
+
+	lvl := river.EstimateAt(r.Value, low, high)
+	return lvl.String(),
+		"Estimated from " + v + " at " + m.Station.Name +
+			" (" + basis + " " + format(low) + " to " + format(high) + ")"
+}
+
+// typicalRange is the station typical range, or the 10th to 90th percentile
+// of recent readings, with a blank basis if neither is available
+func (m Measure) typicalRange() (low float32, high float32, basis string) {
+	s := m.Station
+	if s.Low != nil && s.High != nil && *s.High > *s.Low {
+		return *s.Low, *s.High, "typical range"
+	}
+
+	if len(m.Readings) < minEstimateReadings {
+		return 0, 0, ""
+	}
+	values := make([]float64, len(m.Readings))
+	for i, r := range m.Readings {
+		values[i] = float64(r.Value)
+	}
+	sort.Float64s(values)
+	low = float32(values[len(values)/10])
+	high = float32(values[len(values)*9/10])
+	if high <= low {
+		return 0, 0, ""
+	}
+	return low, high, "recent range"
+}
+
+func format(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', 2, 32)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func estimatedMeasure(values ...float32) Measure {
+	now, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	m := Measure{
+		Station:     gauge.Station{DataURL: "rloi://1", Name: "Bourton Dickler"},
+		Calibration: river.Calibration{URL: "rloi://1", Estimate: true},
+	}
+	for i, v := range values {
+		m.Readings = append(m.Readings, gauge.Reading{
+			EventTime: now.Add(-time.Duration(i) * 15 * time.Minute),
+			Value:     v,
+		})
+	}
+	return m
+}
+
+func TestEstimateFromTypicalRange(t *testing.T) {
+	m := estimatedMeasure(0.55, 0.5)
+	low, high := float32(0.1), float32(0.9)
+	m.Station.Low = &low
+	m.Station.High = &high
+
+	l := m.LatestLevel()
+	if l.Label != river.Low.String() || !l.Estimated {
+		t.Error("Unexpected estimate", l)
+	}
+	if !strings.HasPrefix(l.Reason, "Estimated from 0.55 at Bourton Dickler") || !strings.Contains(l.Reason, "typical range 0.10 to 0.90") {
+		t.Error("Estimate not clear in reason", l.Reason)
+	}
+	if f := m.LatestForecast(); f.Label != "" {
+		t.Error("Estimate should not forecast", f)
+	}
+}
+
+func TestEstimateFromRecentReadings(t *testing.T) {
+	if l := estimatedMeasure(1.0, 0.5, 0.2).LatestLevel(); l.Label != river.Unknown.String() || !strings.Contains(l.Reason, "Not enough readings") {
+		t.Error("Estimated from too few readings", l)
+	}
+
+	// a day of readings from 0.0 to 1.0, with the latest at the top
+	var values []float32
+	for i := minEstimateReadings; i >= 0; i-- {
+		values = append(values, float32(i)/float32(minEstimateReadings))
+	}
+	l := estimatedMeasure(values...).LatestLevel()
+	if l.Label != river.High.String() || !strings.Contains(l.Reason, "recent range") {
+		t.Error("Unexpected estimate", l)
+	}
+}
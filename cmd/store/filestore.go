@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+	"github.com/robtuley/report"
+)
+
+// FileStore keeps records and search objects as JSON files on local disk
+//
+// Records are written to a rivers directory, and search objects to
+// search/rivers and search/stations directories.
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore creates a file store in a directory
+func NewFileStore(dir string) (*FileStore, report.Span) {
+	span := report.StartSpan("filestore.connect").Field("dir", dir)
+
+	for _, sub := range []string{"rivers", "search/rivers", "search/stations"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, span.End(err)
+		}
+	}
+
+	return &FileStore{Dir: dir}, span.End()
+}
+
+// LoadAndUpdate resets the stored record if the section has changed
+func (fs *FileStore) LoadAndUpdate(ctx context.Context, s river.Section) (bool, *Record, report.Span) {
+	span := report.StartSpan("filestore.loadandupdate").Field("uuid", s.UUID)
+	return loadAndUpdate(ctx, fs, s, span)
+}
+
+// Load retrieves a record from disk
+func (fs *FileStore) Load(ctx context.Context, uuid string) (*Record, report.Span) {
+	span := report.StartSpan("filestore.load").Field("uuid", uuid)
+
+	b, err := ioutil.ReadFile(fs.recordPath(uuid))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// the record simply does not exist yet
+			return nil, span.End()
+		}
+		return nil, span.End(err)
+	}
+	var record Record
+	if err := json.Unmarshal(b, &record); err != nil {
+		// as with firestore, assume the data has changed format
+		// and needs to be re-written
+		span = span.Field("corruption", err.Error())
+		return nil, span.End()
+	}
+	return &record, span.End()
+}
+
+// Store saves a record to disk
+func (fs *FileStore) Store(ctx context.Context, record *Record) report.Span {
+	span := report.StartSpan("filestore.store").Field("uuid", record.Section.UUID)
+	return span.End(writeJSON(fs.recordPath(record.Section.UUID), record))
+}
+
+// List retrieves the uuid of every record on disk
+func (fs *FileStore) List(ctx context.Context) ([]string, report.Span) {
+	span := report.StartSpan("filestore.list")
+	uuids, err := listJSON(filepath.Join(fs.Dir, "rivers"))
+	if err != nil {
+		return nil, span.End(err)
+	}
+	return uuids, span.Field("count", len(uuids)).End()
+}
+
+// Delete removes a record from disk
+func (fs *FileStore) Delete(ctx context.Context, uuid string) report.Span {
+	span := report.StartSpan("filestore.delete").Field("uuid", uuid)
+	return span.End(removeFile(fs.recordPath(uuid)))
+}
+
+// StoreRecord indexes a river record on disk
+func (fs *FileStore) StoreRecord(ctx context.Context, record *Record) report.Span {
+	span := report.StartSpan("filestore.search.store").Field("uuid", record.Section.UUID)
+	return span.End(writeJSON(fs.searchPath(record.Section.UUID), recordObject(record)))
+}
+
+// StoreStation indexes a gauge station on disk
+func (fs *FileStore) StoreStation(ctx context.Context, station gauge.Station) report.Span {
+	span := report.StartSpan("filestore.search.store").Field("data_url", station.DataURL)
+	path := filepath.Join(fs.Dir, "search", "stations", url.PathEscape(station.DataURL)+".json")
+	return span.End(writeJSON(path, stationObject(station)))
+}
+
+// ListRecords retrieves the uuid of every river record indexed on disk
+func (fs *FileStore) ListRecords(ctx context.Context) ([]string, report.Span) {
+	span := report.StartSpan("filestore.search.list")
+	uuids, err := listJSON(filepath.Join(fs.Dir, "search", "rivers"))
+	if err != nil {
+		return nil, span.End(err)
+	}
+	return uuids, span.Field("count", len(uuids)).End()
+}
+
+// DeleteRecord removes a river record indexed on disk
+func (fs *FileStore) DeleteRecord(ctx context.Context, uuid string) report.Span {
+	span := report.StartSpan("filestore.search.delete").Field("uuid", uuid)
+	return span.End(removeFile(fs.searchPath(uuid)))
+}
+
+func (fs *FileStore) recordPath(uuid string) string {
+	return filepath.Join(fs.Dir, "rivers", uuid+".json")
+}
+
+func (fs *FileStore) searchPath(uuid string) string {
+	return filepath.Join(fs.Dir, "search", "rivers", uuid+".json")
+}
+
+// listJSON is the name, without extension, of each JSON file in a directory
+// (skipping any temporary files left by an interrupted write)
+func listJSON(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(name, ".json"))
+	}
+	return names, nil
+}
+
+// removeFile deletes a file, ignoring one that is already gone
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeJSON atomically replaces a file with the JSON encoded value
+func writeJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
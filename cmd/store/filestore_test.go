@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func TestFileStoreLoadAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	fs, span := NewFileStore(t.TempDir())
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := river.Section{UUID: "abc", SectionName: "Gorge"}
+	hasChanged, record, span := fs.LoadAndUpdate(ctx, s)
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !hasChanged || record.Level.Label != river.Unknown.String() {
+		t.Fatal("new section not written", hasChanged, record)
+	}
+
+	record.Measures = append(record.Measures, Measure{
+		Station: gauge.Station{DataURL: "rloi://1"},
+	})
+	if err := fs.Store(ctx, record).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// unchanged section keeps the stored measures
+	hasChanged, record, span = fs.LoadAndUpdate(ctx, s)
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if hasChanged || len(record.Measures) != 1 {
+		t.Fatal("unchanged section reset", hasChanged, record)
+	}
+
+	// changed section is reset
+	s.SectionName = "Upper Gorge"
+	hasChanged, record, _ = fs.LoadAndUpdate(ctx, s)
+	if !hasChanged || len(record.Measures) != 0 {
+		t.Fatal("changed section not reset", hasChanged, record)
+	}
+}
+
+func TestFileStoreSearch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	fs, _ := NewFileStore(dir)
+
+	station := gauge.Station{DataURL: "https://example.com/station/1"}
+	if err := fs.StoreStation(ctx, station).Err(); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "search", "stations", "*.json"))
+	if len(matches) != 1 {
+		t.Fatal("station not indexed", matches)
+	}
+
+	record := &Record{Section: river.Section{UUID: "abc"}}
+	if err := fs.StoreRecord(ctx, record).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "search", "rivers", "abc.json")); err != nil {
+		t.Fatal("river not indexed", err)
+	}
+}
+
+func TestFileStoreListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	fs, _ := NewFileStore(t.TempDir())
+
+	for _, uuid := range []string{"abc", "def"} {
+		record := &Record{Section: river.Section{UUID: uuid}}
+		if err := fs.Store(ctx, record).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.StoreRecord(ctx, record).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fs.Delete(ctx, "abc").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.DeleteRecord(ctx, "def").Err(); err != nil {
+		t.Fatal(err)
+	}
+	// deleting again is not an error
+	if err := fs.Delete(ctx, "abc").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, span := fs.List(ctx)
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0] != "def" {
+		t.Error("records not listed", records)
+	}
+	indexed, span := fs.ListRecords(ctx)
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(indexed) != 1 || indexed[0] != "abc" {
+		t.Error("search records not listed", indexed)
+	}
+}
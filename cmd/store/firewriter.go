@@ -7,6 +7,7 @@ import (
 	"cloud.google.com/go/firestore"
 	"github.com/robtuley/rainchasers/internal/river"
 	"github.com/robtuley/report"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -51,37 +52,7 @@ func (fw *FireWriter) LoadAndUpdate(ctx context.Context, s river.Section) (hasCh
 
 	span := report.StartSpan("firestore.loadandupdate").Field("uuid", s.UUID)
 
-	// get existing river data to check against
-	record, sp := fw.Load(ctx, s.UUID)
-	span = span.Child(sp)
-	if err := span.Err(); err != nil {
-		return false, record, span.End()
-	}
-
-	// if river has not changed, do nothing
-	if record != nil {
-		if checksum(s) == checksum(record.Section) {
-			return false, record, span.End()
-		}
-	}
-
-	// write new data with no snapshot gauge readings or state only if river
-	// definition has changed
-	new := Record{
-		Section: s,
-		Level: Level{
-			Label:  river.Unknown.String(),
-			Reason: "Not yet calibrated against nearby gauges",
-		},
-		Measures: make([]Measure, 0),
-	}
-	sp = fw.Store(ctx, &new)
-	span = span.Child(sp)
-	if err := span.Err(); err != nil {
-		return true, &new, span.End()
-	}
-
-	return true, &new, span.End()
+	return loadAndUpdate(ctx, fw, s, span)
 }
 
 // Load retrieves the latest river from firestore
@@ -128,3 +99,39 @@ func (fw *FireWriter) Store(ctx context.Context, record *Record) report.Span {
 
 	return span.End()
 }
+
+// List retrieves the uuid of every river in firestore
+func (fw *FireWriter) List(ctx context.Context) ([]string, report.Span) {
+	ctx, cancel := context.WithTimeout(ctx, fw.Timeout)
+	defer cancel()
+
+	span := report.StartSpan("firestore.list")
+
+	var uuids []string
+	it := fw.Collection.DocumentRefs(ctx)
+	for {
+		doc, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, span.End(err)
+		}
+		uuids = append(uuids, doc.ID)
+	}
+	return uuids, span.Field("count", len(uuids)).End()
+}
+
+// Delete removes a river from firestore
+func (fw *FireWriter) Delete(ctx context.Context, uuid string) report.Span {
+	ctx, cancel := context.WithTimeout(ctx, fw.Timeout)
+	defer cancel()
+
+	span := report.StartSpan("firestore.delete").Field("uuid", uuid)
+	_, err := fw.Collection.Doc(uuid).Delete(ctx)
+	if err != nil {
+		return span.End(err)
+	}
+
+	return span.End()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// forecastHorizon is the furthest ahead a change of level is predicted
+const forecastHorizon = 24 * time.Hour
+
+// Forecast is the expected next change of section level
+type Forecast struct {
+	Label        string    `firestore:"label"`         // level expected next e.g. "low"
+	ExpectedTime time.Time `firestore:"expected_time"` // time the level is expected to change
+	Reason       string    `firestore:"reason"`        // e.g. "expected to drop below low in ~5h"
+}
+
+// threshold is the minimum gauge value for a level
+type threshold struct {
+	Level river.Level
+	Value float32
+}
+
+// LatestForecast extrapolates the recent trend of the readings to estimate
+// when the level will next cross into a different calibrated band
+//
+// A rise is extrapolated linearly as it depends on rainfall the gauge cannot
+// know about, while a fall follows a recession curve if one can be fitted.
+func (m Measure) LatestForecast() Forecast {
+	if len(m.Readings) < 2 || len(m.Calibration.Minimum) == 0 || m.Calibration.IsRainfall() {
+		return Forecast{}
+	}
+
+	var thresholds []threshold
+	for strLevel, minValue := range m.Calibration.Minimum {
+		thresholds = append(thresholds, threshold{
+			Level: river.StringToLevel(strLevel),
+			Value: minValue,
+		})
+	}
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].Value < thresholds[j].Value
+	})
+
+	r := m.Readings[0]
+	trend, rate := trendOf(m.Readings, m.Station.Unit)
+	current := m.Calibration.LevelAt(r.Value)
+
+	var next threshold
+	var hours float64
+	switch trend {
+	case river.Rising:
+		// find the first threshold above the current value
+		found := false
+		for _, t := range thresholds {
+			if t.Value > r.Value && t.Level != current {
+				next = t
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Forecast{}
+		}
+		hours = float64(next.Value-r.Value) / float64(rate)
+	case river.Falling:
+		// find the threshold of the current band, the level will
+		// drop into the band below when it goes under this value
+		found := false
+		next = threshold{Level: river.Empty}
+		for i := len(thresholds) - 1; i >= 0; i-- {
+			if thresholds[i].Value <= r.Value {
+				next.Value = thresholds[i].Value
+				found = true
+				if i > 0 {
+					next.Level = thresholds[i-1].Level
+				}
+				break
+			}
+		}
+		if !found {
+			return Forecast{}
+		}
+		hours = hoursToFallTo(m.Readings, m.Station.Unit, rate, next.Value)
+		if hours < 0 {
+			return Forecast{}
+		}
+	default:
+		return Forecast{}
+	}
+
+	expected := r.EventTime.Add(time.Duration(hours * float64(time.Hour)))
+	if expected.Sub(r.EventTime) > forecastHorizon {
+		return Forecast{}
+	}
+
+	reason := "expected to rise to " + next.Level.String()
+	if trend == river.Falling {
+		reason = "expected to drop below " + current.String()
+	}
+	return Forecast{
+		Label:        next.Level.String(),
+		ExpectedTime: expected,
+		Reason:       reason + " in " + approxDuration(expected.Sub(r.EventTime)),
+	}
+}
+
+// hoursToFallTo estimates the hours until falling readings drop to a value,
+// or a negative number if they are not expected to get that low
+//
+// Rivers recede exponentially towards their base flow, so if the rate of fall
+// is slowing the readings are fitted to an exponential recession using the
+// rate now and one trend window earlier. Otherwise the fall is linear.
+func hoursToFallTo(readings []gauge.Reading, unit string, rate float32, value float32) float64 {
+	latest := readings[0]
+	drop := float64(latest.Value - value)
+	linear := drop / float64(-rate)
+
+	var earlier []gauge.Reading
+	for i, r := range readings {
+		if latest.EventTime.Sub(r.EventTime) >= trendWindow {
+			earlier = readings[i:]
+			break
+		}
+	}
+	earlierTrend, earlierRate := trendOf(earlier, unit)
+	if earlierTrend != river.Falling || earlierRate >= rate {
+		return linear
+	}
+
+	// the time constant of the recession is how long it takes the rate of
+	// fall to slow by a factor of e, and the rates were fitted across each
+	// window so are adjusted forward to the latest reading
+	τ := trendWindow.Hours() / math.Log(float64(earlierRate/rate))
+	currentRate := -float64(rate) * math.Exp(-trendWindow.Hours()/2/τ)
+	remainingFall := currentRate * τ
+	if drop >= remainingFall {
+		return -1
+	}
+	return -τ * math.Log(1-drop/remainingFall)
+}
+
+// approxDuration formats a duration as a rough human readable period
+func approxDuration(d time.Duration) string {
+	if d < time.Hour {
+		return "under 1h"
+	}
+	return "~" + strconv.Itoa(int(math.Round(d.Hours()))) + "h"
+}
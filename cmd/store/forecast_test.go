@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/ea"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func TestForecastRisingAndFalling(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	m := Measure{
+		Calibration: river.Calibration{
+			Minimum: map[string]float32{
+				river.Low.String():    1.0,
+				river.Medium.String(): 2.0,
+			},
+		},
+	}
+
+	// rising at 0.4/hr from 1.4 should reach medium in ~1.5h
+	m.Readings = readingsEvery15Min(start, 1.0, 1.1, 1.2, 1.3, 1.4)
+	f := m.LatestForecast()
+	if f.Label != river.Medium.String() {
+		t.Error("rising forecast label", f)
+	}
+	if δ := f.ExpectedTime.Sub(start.Add(time.Hour + 90*time.Minute)); δ > time.Minute || δ < -time.Minute {
+		t.Error("rising forecast time", f.ExpectedTime)
+	}
+
+	// falling at 0.4/hr from 1.6 should drop below low in ~1.5h
+	m.Readings = readingsEvery15Min(start, 2.0, 1.9, 1.8, 1.7, 1.6)
+	f = m.LatestForecast()
+	if f.Label != river.Empty.String() || f.Reason != "expected to drop below low in ~2h" {
+		t.Error("falling forecast", f)
+	}
+
+	// steady readings have no forecast
+	m.Readings = readingsEvery15Min(start, 1.5, 1.5, 1.5)
+	if f = m.LatestForecast(); !f.ExpectedTime.IsZero() {
+		t.Error("steady forecast", f)
+	}
+}
+
+// TestForecastAgainstArchive replays a spate from an EA daily archive one
+// reading at a time and checks forecasts on the recession against what
+// actually happened. Rises depend on rainfall, and just after a peak there
+// is not enough of a fall to fit a recession, so neither are checked.
+func TestForecastAgainstArchive(t *testing.T) {
+	const url = "http://environment.data.gov.uk/flood-monitoring/id/measures/2077-level-stage-i-15_min-m"
+	const tolerance = time.Hour
+	const nearTerm = 6 * time.Hour
+
+	f, err := os.Open("testdata/readings-2020-02-15.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	archive, err := ea.ParseDay(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := merge(nil, archive[url])
+	if len(history) == 0 {
+		t.Fatal("no readings in archive")
+	}
+
+	cal := river.Calibration{
+		Minimum: map[string]float32{
+			river.Low.String():    0.5,
+			river.Medium.String(): 0.8,
+			river.High.String():   1.2,
+		},
+	}
+
+	nChecked := 0
+	for i := len(history) - 2; i >= 0; i-- {
+		m := Measure{
+			Calibration: cal,
+			Readings:    history[i:],
+		}
+		forecast := m.LatestForecast()
+		now := history[i].EventTime
+		if forecast.ExpectedTime.IsZero() || forecast.ExpectedTime.Sub(now) > nearTerm {
+			continue
+		}
+		if trend, _ := trendOf(m.Readings, m.Station.Unit); trend != river.Falling {
+			continue
+		}
+		if now.Sub(lastPeakOf(m.Readings)) < 2*trendWindow {
+			continue
+		}
+
+		actual, ok := firstTimeAtLevel(history[:i], cal, river.StringToLevel(forecast.Label))
+		if !ok {
+			t.Error(now, forecast.Reason, "but never happened")
+			continue
+		}
+		if δ := actual.Sub(forecast.ExpectedTime); δ > tolerance || δ < -tolerance {
+			t.Error(now, forecast.Reason, "but happened at", actual)
+		}
+		nChecked++
+	}
+	if nChecked < 10 {
+		t.Error("too few forecasts checked", nChecked)
+	}
+}
+
+func firstTimeAtLevel(newestFirst []gauge.Reading, cal river.Calibration, lvl river.Level) (time.Time, bool) {
+	for i := len(newestFirst) - 1; i >= 0; i-- {
+		if cal.LevelAt(newestFirst[i].Value) == lvl {
+			return newestFirst[i].EventTime, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+// LevelAt returns the level on the section at a time, using the reading
+// that has had time to travel between the gauge and the section
+//
+// The level event time is when the reading reaches the section.
+func (m Measure) LevelAt(now time.Time) Level {
+	if m.Calibration.Lag == 0 {
+		return m.LatestLevel()
+	}
+
+	arrived, _ := m.lagged(now)
+	l := arrived.LatestLevel()
+	if len(arrived.Readings) > 0 {
+		l.Reason += " (" + lagDescription(m.Calibration.Lag) + ")"
+	}
+	return l
+}
+
+// ForecastAt returns the next expected change of level on the section
+//
+// Readings from an upstream gauge that have yet to reach the section give
+// the next change directly, otherwise the trend is extrapolated.
+func (m Measure) ForecastAt(now time.Time) Forecast {
+	if m.Calibration.Lag == 0 {
+		return m.LatestForecast()
+	}
+
+	arrived, due := m.lagged(now)
+	if len(arrived.Readings) > 0 && len(m.Calibration.Minimum) > 0 {
+		current := m.Calibration.LevelAt(arrived.Readings[0].Value)
+		for i := len(due) - 1; i >= 0; i-- {
+			next := m.Calibration.LevelAt(due[i].Value)
+			if next == current {
+				continue
+			}
+			reason := "expected to rise to " + next.String()
+			if next < current {
+				reason = "expected to drop below " + current.String()
+			}
+			return Forecast{
+				Label:        next.String(),
+				ExpectedTime: due[i].EventTime,
+				Reason:       reason + " in " + approxDuration(due[i].EventTime.Sub(now)),
+			}
+		}
+	}
+
+	shifted := m
+	shifted.Readings = append(due, arrived.Readings...)
+	return shifted.LatestForecast()
+}
+
+// lagged shifts the readings to the time they reach the section, split
+// into those that have arrived by a time and those still due, newest first
+//
+// If no readings have arrived the oldest due reading is used, as the best
+// available until the gauge has been read for longer than the lag.
+func (m Measure) lagged(now time.Time) (arrived Measure, due []gauge.Reading) {
+	arrived = m
+	arrived.Readings = nil
+	for _, r := range m.Readings {
+		r.EventTime = r.EventTime.Add(m.Calibration.Lag)
+		if r.EventTime.After(now) {
+			due = append(due, r)
+		} else {
+			arrived.Readings = append(arrived.Readings, r)
+		}
+	}
+	if len(arrived.Readings) == 0 && len(due) > 0 {
+		arrived.Readings = due[len(due)-1:]
+		due = due[:len(due)-1]
+	}
+	return arrived, due
+}
+
+func lagDescription(lag time.Duration) string {
+	if lag < 0 {
+		return "gauge " + approxDuration(-lag) + " downstream"
+	}
+	return "gauge " + approxDuration(lag) + " upstream"
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func laggedMeasure(lag time.Duration, readings []gauge.Reading) Measure {
+	return Measure{
+		Station: gauge.Station{Name: "Upstream"},
+		Calibration: river.Calibration{
+			Lag: lag,
+			Minimum: map[string]float32{
+				river.Low.String():    1.0,
+				river.Medium.String(): 2.0,
+			},
+		},
+		Readings: readings,
+	}
+}
+
+func TestLevelFromUpstreamGauge(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:00:00Z")
+	// a rise at the gauge over the last hour, every 15 mins
+	readings := readingsEvery15Min(start, 1.5, 1.6, 1.8, 2.1, 2.4)
+	now := start.Add(time.Hour)
+	m := laggedMeasure(45*time.Minute, readings)
+
+	l := m.LevelAt(now)
+	if l.Label != river.Low.String() || l.Reason != "1.60 at Upstream (gauge under 1h upstream)" {
+		t.Error("Unexpected lagged level", l)
+	}
+	if !l.EventTime.Equal(now) {
+		t.Error("Event time not lagged", l.EventTime)
+	}
+
+	// the rise to medium at the gauge reaches the section after the lag
+	f := m.ForecastAt(now)
+	if f.Label != river.Medium.String() || !f.ExpectedTime.Equal(start.Add(90*time.Minute)) {
+		t.Error("Unexpected lagged forecast", f)
+	}
+
+	if l := laggedMeasure(0, readings).LevelAt(now); l.Label != river.Medium.String() {
+		t.Error("Unlagged level not latest", l)
+	}
+}
+
+func TestLevelFromDownstreamGauge(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:00:00Z")
+	readings := readingsEvery15Min(start, 1.5, 1.6, 1.8, 2.1, 2.4)
+	now := start.Add(time.Hour)
+
+	l := laggedMeasure(-2*time.Hour, readings).LevelAt(now)
+	if l.Label != river.Medium.String() || l.Reason != "2.40 at Upstream (gauge ~2h downstream)" {
+		t.Error("Unexpected lagged level", l)
+	}
+	if !l.EventTime.Equal(start.Add(-time.Hour)) {
+		t.Error("Event time not lagged", l.EventTime)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// staleAfter is the age at which a gauge reading is no longer considered
+// current enough to decide the section level if a fresher one is available
+const staleAfter = 6 * time.Hour
+
+// candidate is the level opinion of a single calibrated gauge
+type candidate struct {
+	Name     string
+	Level    Level
+	Forecast Forecast
+}
+
+// resolveLevel combines the latest level from every measure in the record
+// into a single section level using the section rule, along with the
+// forecast from the gauge that decided it
+func resolveLevel(rule river.Rule, calibrations []river.Calibration, measures []Measure, now time.Time) (Level, Forecast) {
+	if len(measures) == 0 {
+		return Level{
+			Label:  river.Unknown.String(),
+			Reason: "Not yet calibrated against nearby gauges",
+		}, Forecast{}
+	}
+
+	// order the gauge opinions to match the calibration order so the
+	// primary gauge is always considered first
+	var all []candidate
+	var names []string
+	for _, cal := range calibrations {
+		for _, m := range measures {
+			if m.Calibration.URL != cal.URL || m.Calibration.Type != cal.Type {
+				continue
+			}
+			names = append(names, m.Station.Name)
+			if len(m.Readings) == 0 {
+				continue
+			}
+			all = append(all, candidate{
+				Name:     m.Station.Name,
+				Level:    m.LevelAt(now),
+				Forecast: m.ForecastAt(now),
+			})
+		}
+	}
+	if len(all) == 0 {
+		return Level{
+			Label:  river.Unknown.String(),
+			Reason: "No readings currently available from " + strings.Join(names, ", "),
+		}, Forecast{}
+	}
+
+	// only use stale readings if there is nothing more recent
+	var fresh []candidate
+	for _, c := range all {
+		if now.Sub(c.Level.EventTime) <= staleAfter {
+			fresh = append(fresh, c)
+		}
+	}
+	if len(fresh) == 0 {
+		fresh = all
+	}
+	if len(fresh) == 1 && len(names) == 1 {
+		return fresh[0].Level, fresh[0].Forecast
+	}
+
+	var chosen candidate
+	switch river.StringToRule(string(rule)) {
+	case river.Latest:
+		chosen = latestOf(fresh)
+	case river.Worst:
+		chosen = worstOf(fresh)
+	case river.Agree:
+		chosen = agreementOf(fresh)
+	default:
+		chosen = primaryOf(fresh, names[0])
+	}
+	return chosen.Level, chosen.Forecast
+}
+
+func primaryOf(cs []candidate, primaryName string) candidate {
+	chosen := cs[0]
+	if chosen.Name != primaryName {
+		chosen.Level.Reason += " (no recent readings from " + primaryName + ")"
+	}
+	return chosen
+}
+
+func latestOf(cs []candidate) candidate {
+	chosen := cs[0]
+	for _, c := range cs[1:] {
+		if c.Level.EventTime.After(chosen.Level.EventTime) {
+			chosen = c
+		}
+	}
+	chosen.Level.Reason += " (most recent of " + joinNames(cs) + ")"
+	return chosen
+}
+
+func worstOf(cs []candidate) candidate {
+	chosen := cs[0]
+	for _, c := range cs[1:] {
+		lvl := river.StringToLevel(c.Level.Label)
+		chosenLvl := river.StringToLevel(chosen.Level.Label)
+		if lvl > chosenLvl || (lvl == chosenLvl && c.Level.EventTime.After(chosen.Level.EventTime)) {
+			chosen = c
+		}
+	}
+	chosen.Level.Reason += " (highest of " + joinNames(cs) + ")"
+	return chosen
+}
+
+func agreementOf(cs []candidate) candidate {
+	chosen := latestOf(cs)
+	var reasons []string
+	for _, c := range cs {
+		reasons = append(reasons, c.Level.Reason+" is "+c.Level.Label)
+		if c.Level.Label != cs[0].Level.Label {
+			chosen.Level.Label = river.Unknown.String()
+		}
+	}
+	if chosen.Level.Label == river.Unknown.String() {
+		chosen.Level.Reason = "Gauges disagree: " + strings.Join(reasons, ", ")
+		chosen.Forecast = Forecast{}
+		return chosen
+	}
+	chosen.Level.Reason = "Gauges agree: " + strings.Join(reasons, ", ")
+	return chosen
+}
+
+func joinNames(cs []candidate) string {
+	var names []string
+	for _, c := range cs {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func calibratedMeasure(url string, name string, value float32, at time.Time) Measure {
+	return Measure{
+		Station: gauge.Station{
+			DataURL:  url,
+			AliasURL: url,
+			Name:     name,
+		},
+		Calibration: river.Calibration{
+			URL: url,
+			Minimum: map[string]float32{
+				river.Low.String():    1.0,
+				river.Medium.String(): 2.0,
+				river.High.String():   3.0,
+			},
+		},
+		Readings: []gauge.Reading{{EventTime: at, Value: value}},
+	}
+}
+
+func TestResolveLevelRules(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	calibrations := []river.Calibration{
+		{URL: "rloi://1"},
+		{URL: "rloi://2"},
+	}
+	// measures arrive in snapshot order, not calibration order
+	measures := []Measure{
+		calibratedMeasure("rloi://2", "Downstream", 3.5, now.Add(-time.Minute)),
+		calibratedMeasure("rloi://1", "Upstream", 1.5, now.Add(-time.Hour)),
+	}
+
+	expect := map[river.Rule]string{
+		"":           river.Low.String(),
+		river.Latest: river.High.String(),
+		river.Worst:  river.High.String(),
+		river.Agree:  river.Unknown.String(),
+	}
+	for rule, label := range expect {
+		l, _ := resolveLevel(rule, calibrations, measures, now)
+		if l.Label != label {
+			t.Error(rule, "resolved to", l.Label, l.Reason)
+		}
+		if !strings.Contains(l.Reason, "Upstream") {
+			t.Error(rule, "reason does not name contributing gauges", l.Reason)
+		}
+	}
+}
+
+func TestResolveLevelFallsBackFromStalePrimary(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	calibrations := []river.Calibration{
+		{URL: "rloi://1"},
+		{URL: "rloi://2"},
+	}
+	measures := []Measure{
+		calibratedMeasure("rloi://1", "Upstream", 1.5, now.Add(-24*time.Hour)),
+		calibratedMeasure("rloi://2", "Downstream", 2.5, now.Add(-time.Minute)),
+	}
+
+	l, _ := resolveLevel(river.Primary, calibrations, measures, now)
+	if l.Label != river.Medium.String() {
+		t.Error("stale primary gauge used", l.Label, l.Reason)
+	}
+	if !strings.Contains(l.Reason, "no recent readings from Upstream") {
+		t.Error("fallback not explained", l.Reason)
+	}
+}
+
+func TestResolveLevelAgreement(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	calibrations := []river.Calibration{
+		{URL: "rloi://1"},
+		{URL: "rloi://2"},
+	}
+	measures := []Measure{
+		calibratedMeasure("rloi://1", "Upstream", 2.1, now.Add(-time.Hour)),
+		calibratedMeasure("rloi://2", "Downstream", 2.9, now.Add(-time.Minute)),
+	}
+
+	l, _ := resolveLevel(river.Agree, calibrations, measures, now)
+	if l.Label != river.Medium.String() {
+		t.Error("agreeing gauges not resolved", l.Label, l.Reason)
+	}
+	if !l.EventTime.Equal(now.Add(-time.Minute)) {
+		t.Error("agreement not timed at most recent reading", l.EventTime)
+	}
+}
+
+func TestResolveLevelWithoutReadings(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	calibrations := []river.Calibration{{URL: "rloi://1"}}
+	m := calibratedMeasure("rloi://1", "Upstream", 1.5, now)
+	m.Readings = nil
+
+	l, _ := resolveLevel(river.Primary, calibrations, []Measure{m}, now)
+	if l.Label != river.Unknown.String() {
+		t.Error("level without readings", l.Label)
+	}
+}
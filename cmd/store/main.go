@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"os"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/robtuley/rainchasers"
@@ -14,21 +16,48 @@ import (
 	"github.com/robtuley/report"
 )
 
+// Responds to environment variables:
+//   PROJECT_ID (no default, blank for local storage)
+//   PUBSUB_TOPIC (no default)
+//   ALGOLIA_APP_ID (no default)
+//   ALGOLIA_API_KEY (no default)
+//   STORE_DIR (no default, blank for in-memory storage if no PROJECT_ID)
+//   QUEUE_DIR (no default, subscribe to a local file queue instead of pubsub)
+//   DEADLETTER_TOPIC (no default, publish rejected snapshots to this topic)
+//   DEADLETTER_FILE (no default, append rejected snapshots to a local file)
+//   DEADLETTER_UNROUTED (no default, "true" to also reject unrouted snapshots)
+//   CONTENT_DIR (no default, blank for the content compiled into the binary)
+//   RECONCILE_DELETE (no default, "true" to delete records of removed sections)
 func main() {
 	d := daemon.New("firestore")
+	content, err := rainchasers.LoadContent(os.Getenv("CONTENT_DIR"), d.Logger)
+	if err != nil {
+		d.Action("content.invalid", report.Data{
+			"error": err.Error(),
+		})
+		d.CloseWait()
+		os.Exit(1)
+	}
 	app := &cache{
-		ProjectID:      os.Getenv("PROJECT_ID"),
-		TopicName:      os.Getenv("PUBSUB_TOPIC"),
-		AlgoliaAppID:   os.Getenv("ALGOLIA_APP_ID"),
-		AlgoliaAPIKey:  os.Getenv("ALGOLIA_API_KEY"),
-		ReadyC:         make(chan struct{}),
-		Log:            d.Logger,
-		SnapRoute:      make(map[string][]chan *gauge.Snapshot),
-		StationUpdated: make(map[string]bool),
+		ProjectID:       os.Getenv("PROJECT_ID"),
+		TopicName:       os.Getenv("PUBSUB_TOPIC"),
+		AlgoliaAppID:    os.Getenv("ALGOLIA_APP_ID"),
+		AlgoliaAPIKey:   os.Getenv("ALGOLIA_API_KEY"),
+		StoreDir:        os.Getenv("STORE_DIR"),
+		QueueDir:        os.Getenv("QUEUE_DIR"),
+		DeadLetterTopic: os.Getenv("DEADLETTER_TOPIC"),
+		DeadLetterFile:  os.Getenv("DEADLETTER_FILE"),
+		RejectUnrouted:  os.Getenv("DEADLETTER_UNROUTED") == "true",
+		ReconcileDelete: os.Getenv("RECONCILE_DELETE") == "true",
+		Content:         content,
+		ReadyC:          make(chan struct{}),
+		Log:             d.Logger,
+		StationUpdated:  make(map[string]bool),
 	}
 
 	d.Run(context.Background(), app.Init)
 	d.Run(context.Background(), app.SubscribeToSnapshots)
+	d.Run(context.Background(), app.ReloadContent)
 	d.CloseAfter(24 * time.Hour)
 
 	d.Wait()
@@ -39,99 +68,129 @@ func main() {
 }
 
 type cache struct {
-	ProjectID      string
-	TopicName      string
-	AlgoliaAppID   string
-	AlgoliaAPIKey  string
-	ReadyC         chan struct{}
-	Log            *report.Logger
-	FireWriter     *FireWriter
-	AlgoliaWriter  *AlgoliaWriter
-	SnapRoute      map[string][]chan *gauge.Snapshot
-	StationUpdated map[string]bool
+	ProjectID       string
+	TopicName       string
+	AlgoliaAppID    string
+	AlgoliaAPIKey   string
+	StoreDir        string
+	QueueDir        string
+	DeadLetterTopic string
+	DeadLetterFile  string
+	RejectUnrouted  bool
+	ReconcileDelete bool
+	UpdateEvery     time.Duration
+	Content         *river.Loader
+	ReadyC          chan struct{}
+	Log             *report.Logger
+	Records         RecordStore
+	Search          SearchSink
+	Topic           *queue.Topic
+	SnapRoute       map[string][]*sectionWriter
+	StationUpdated  map[string]bool
+
+	mu      sync.RWMutex // guards SnapRoute, which changes on reload
+	writers map[string]*sectionWriter
 }
 
 func (c *cache) Init(ctx context.Context, d *daemon.Supervisor) error {
-	// quit before any firestore prep if in dry run
-	if c.ProjectID == "" {
-		close(c.ReadyC)
-		return nil
+	// connect to storage unless already provided
+	if c.Records == nil {
+		if err := c.connect(d); err != nil {
+			return err
+		}
 	}
 
-	// connect to firestore & algolia
-	fw, span := NewFireWriter(c.ProjectID)
-	d.Trace(span)
-	if err := span.Err(); err != nil {
+	// use the compiled content unless loaded at runtime
+	if c.Content == nil {
+		c.Content = river.NewStaticLoader(rainchasers.Compiled())
+	}
+	if err := c.applyContent(ctx, d, c.Content.Content()); err != nil {
 		return err
 	}
-	c.FireWriter = fw
-	c.AlgoliaWriter = NewAlgoliaWriter(c.AlgoliaAppID, c.AlgoliaAPIKey)
-
-	// update catalogue in firestore (rate limited)
-	ticker := time.NewTicker(50 * time.Millisecond)
-	defer ticker.Stop()
-updateLoop:
-	for _, s := range rainchasers.Sections {
-		// get firestore info for the section
-		// (& update if necessary and in algolia if changed)
-		hasChanged, record, span := c.FireWriter.LoadAndUpdate(ctx, s)
-		if hasChanged {
-			aSpan := c.AlgoliaWriter.StoreRecord(ctx, record)
-			span = span.FollowedBy(aSpan)
-		}
+
+	close(c.ReadyC)
+	return nil
+}
+
+// connect chooses the storage backend: firestore & algolia if there is a
+// project, otherwise a local file store, or in-memory if no directory is set
+func (c *cache) connect(d *daemon.Supervisor) error {
+	switch {
+	case c.ProjectID != "":
+		fw, span := NewFireWriter(c.ProjectID)
 		d.Trace(span)
 		if err := span.Err(); err != nil {
 			return err
 		}
-
-		// if calibration exists then launch goroutine
-		// to listen to snapshots and update river
-		calibrations, isCalibrated := rainchasers.Calibrations[s.UUID]
-		if isCalibrated {
-			ch := make(chan *gauge.Snapshot)
-
-			// add to routing table
-			for _, m := range calibrations {
-				c.SnapRoute[m.URL] = append(c.SnapRoute[m.URL], ch)
-			}
-
-			fn := c.CreateSnapshotsWriter(*record, calibrations, ch)
-			d.Run(context.Background(), fn)
-		}
-
-		select {
-		case <-ctx.Done():
-			break updateLoop
-		case <-ticker.C:
+		c.Records = fw
+		c.Search = NewAlgoliaWriter(c.AlgoliaAppID, c.AlgoliaAPIKey)
+		c.UpdateEvery = 50 * time.Millisecond
+	case c.StoreDir != "":
+		fs, span := NewFileStore(c.StoreDir)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
 		}
+		c.Records = fs
+		c.Search = fs
+	default:
+		ms := NewMemoryStore()
+		c.Records = ms
+		c.Search = ms
 	}
-
-	close(c.ReadyC)
 	return nil
 }
 
-func (c *cache) CreateSnapshotsWriter(record Record, calibrations []river.Calibration, ch chan *gauge.Snapshot) func(ctx context.Context, d *daemon.Supervisor) error {
+func (c *cache) CreateSnapshotsWriter(record Record, calibrations []river.Calibration, calendar river.Calendar, ch chan *gauge.Snapshot) func(ctx context.Context, d *daemon.Supervisor) error {
 	return func(ctx context.Context, d *daemon.Supervisor) error {
 		// the calibrations may have changed on previously inited measures
 		// that have been pulled from firestore, so reset the calibrations
 		// stored against each measure. if a calibration no longer exists, the
 		// measure should be deleted.
-		for i := range record.Measures {
-			cal, exists := findCalibrationForStation(calibrations, record.Measures[i].Station)
+		// (compared directly as a checksum of the threshold maps is not stable)
+		hasChanged := false
+		measures := make([]Measure, 0, len(record.Measures))
+		for _, m := range record.Measures {
+			cal, exists := findCalibrationForStation(calibrations, m.Station)
+			if !exists || !reflect.DeepEqual(cal, m.Calibration) {
+				hasChanged = true
+			}
 			if exists {
-				record.Measures[i].Calibration = cal
-			} else {
-				// delete this index from the slice
-				record.Measures = append(record.Measures[:i], record.Measures[i+1:]...)
+				m.Calibration = cal
+				measures = append(measures, m)
 			}
 		}
+		record.Measures = deriveMeasures(calibrations, measures)
+
+		// re-evaluate the level straight away if the calibrations changed
+		if hasChanged {
+			now := time.Now()
+			record.Level, record.Forecast = resolveLevel(record.Section.LevelRule, calibrations, record.Measures, now)
+			record.Level, record.Forecast = releaseLevel(calendar, record.Level, record.Forecast, now)
+			c.storeRecord(ctx, &record, "calibration.changed")
+		}
 
 		// now we have updated the measures, we start building the map of where to
 		// route snapshots too. Where a snapshot misses (i.e. a new measure), this
-		// is lazy-inited as we need to wait for a snapshot with the station info in
-		aliasURLToIndex := make(map[string]int)
+		// is lazy-inited as we need to wait for a snapshot with the station info in.
+		// The type is part of the key as a station alias is shared by its level
+		// and flow measures.
+		measureToIndex := make(map[string]int)
 		for i, m := range record.Measures {
-			aliasURLToIndex[m.Station.AliasURL] = i
+			measureToIndex[measureKey(m.Station)] = i
+		}
+
+		// a scheduled release starting or ending changes the level without
+		// any snapshot, so wake up at the next change in the calendar
+		nextRelease := func() <-chan time.Time {
+			if at, ok := calendar.NextChange(time.Now()); ok {
+				return time.After(time.Until(at))
+			}
+			return nil
+		}
+		releaseC := nextRelease()
+		if len(calendar.Releases) > 0 {
+			c.storeLevel(ctx, &record, calibrations, calendar, "release.updated")
 		}
 
 	nextSnapshot:
@@ -144,23 +203,35 @@ func (c *cache) CreateSnapshotsWriter(record Record, calibrations []river.Calibr
 			case <-ticker.C:
 				// if no snapshot received for some time there is
 				// some sort of upstream problem
-				c.Log.Action("snapshot.missing", report.Data{
-					"section_uuid": record.Section.UUID,
-				})
+				if len(calibrations) > 0 {
+					c.Log.Action("snapshot.missing", report.Data{
+						"section_uuid": record.Section.UUID,
+					})
+				}
 				ticker.Stop()
 				continue nextSnapshot
+			case <-releaseC:
+				ticker.Stop()
+				releaseC = nextRelease()
+				c.storeLevel(ctx, &record, calibrations, calendar, "release.updated")
+				continue nextSnapshot
 			case snap = <-ch:
 			}
 			ticker.Stop()
 
 			// route snap to existing or create new measure
-			index, ok := aliasURLToIndex[snap.Station.AliasURL]
+			index, ok := measureToIndex[measureKey(snap.Station)]
 			if !ok {
 				// this must be the first snapshot of a lazy-inited measure
 				// so we need to search for the calibration and then setup
 				// the new measure ready to receive further snapshots
 				cal, exists := findCalibrationForStation(calibrations, snap.Station)
 				if !exists {
+					if isRoutedToCalibration(calibrations, snap.Station) {
+						// another measure at a calibrated station, such as
+						// flow when the section is calibrated on level
+						continue nextSnapshot
+					}
 					// this must be code logic as this routine should only receive
 					// snaps that have a calibration for (even if that calibration is empty)
 					msg := record.Section.UUID + " with snap " + snap.Station.AliasURL
@@ -175,7 +246,7 @@ func (c *cache) CreateSnapshotsWriter(record Record, calibrations []river.Calibr
 					Calibration: cal,
 					// no readings
 				})
-				aliasURLToIndex[snap.Station.AliasURL] = index
+				measureToIndex[measureKey(snap.Station)] = index
 			}
 
 			// now we know the index we're putting this snapshot into (and have
@@ -210,19 +281,45 @@ func (c *cache) CreateSnapshotsWriter(record Record, calibrations []river.Calibr
 			}
 			m.ProcessedTime = snap.ProcessedTime
 			record.Measures[index] = m
+			record.Measures = deriveMeasures(calibrations, record.Measures)
 
-			// use updated measure to re-calulate level state
-			record.Level = m.LatestLevel()
+			// use all measures to re-calculate the section level state
+			now := time.Now()
+			record.Level, record.Forecast = resolveLevel(record.Section.LevelRule, calibrations, record.Measures, now)
+			record.Level, record.Forecast = releaseLevel(calendar, record.Level, record.Forecast, now)
 
-			// write the update to firestore & algolia
-			fSpan := c.FireWriter.Store(ctx, &record)
-			aSpan := c.AlgoliaWriter.StoreRecord(ctx, &record)
+			// write the update to storage & search
+			fSpan := c.Records.Store(ctx, &record)
+			aSpan := c.Search.StoreRecord(ctx, &record)
 			span = span.Child(fSpan).Child(aSpan)
 			c.Log.Trace(span.End())
 		}
 	}
 }
 
+// storeLevel re-evaluates the level of a section from its current measures
+// and any release calendar, writing to storage & search if it has changed
+func (c *cache) storeLevel(ctx context.Context, record *Record, calibrations []river.Calibration, calendar river.Calendar, name string) {
+	now := time.Now()
+	lvl, forecast := resolveLevel(record.Section.LevelRule, calibrations, record.Measures, now)
+	lvl, forecast = releaseLevel(calendar, lvl, forecast, now)
+	if checksum(lvl.Label, lvl.Reason, forecast) == checksum(record.Level.Label, record.Level.Reason, record.Forecast) {
+		return
+	}
+	record.Level, record.Forecast = lvl, forecast
+	c.storeRecord(ctx, record, name)
+}
+
+// storeRecord writes a record to storage & search
+func (c *cache) storeRecord(ctx context.Context, record *Record, name string) {
+	span := report.StartSpan(name)
+	span = span.Field("section_uuid", record.Section.UUID)
+	fSpan := c.Records.Store(ctx, record)
+	aSpan := c.Search.StoreRecord(ctx, record)
+	span = span.Child(fSpan).Child(aSpan)
+	c.Log.Trace(span.End())
+}
+
 func (c *cache) SubscribeToSnapshots(ctx context.Context, d *daemon.Supervisor) error {
 	// wait for init
 	select {
@@ -231,16 +328,49 @@ func (c *cache) SubscribeToSnapshots(ctx context.Context, d *daemon.Supervisor)
 	case <-c.ReadyC:
 	}
 
-	// connect to pubsub
-	topic, span := queue.New(ctx, c.ProjectID, c.TopicName)
-	d.Trace(span)
-	if err := span.Err(); err != nil {
+	// connect to the queue unless already provided
+	if c.Topic == nil {
+		topic, span := queue.Open(ctx, c.ProjectID, c.QueueDir, c.TopicName)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
+		}
+		defer topic.Stop()
+		c.Topic = topic
+	}
+
+	// send rejected snapshots to a dead-letter sink if configured
+	sink, err := c.connectDeadLetters(ctx, d)
+	if err != nil {
 		return err
 	}
-	defer topic.Stop()
+	if sink != nil {
+		defer sink.Stop()
+		c.Topic.DeadLetterTo(sink)
+	}
 
 	// subscribe!
-	return topic.Subscribe(ctx, "", c.SnapshotRouter)
+	return c.Topic.Subscribe(ctx, "", c.SnapshotRouter)
+}
+
+func (c *cache) connectDeadLetters(ctx context.Context, d *daemon.Supervisor) (queue.DeadLetterSink, error) {
+	switch {
+	case c.DeadLetterTopic != "":
+		sink, span := queue.OpenDeadLetterTopic(ctx, c.ProjectID, c.QueueDir, c.DeadLetterTopic)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return nil, err
+		}
+		return sink, nil
+	case c.DeadLetterFile != "":
+		sink, span := queue.NewDeadLetterFile(c.DeadLetterFile)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return nil, err
+		}
+		return sink, nil
+	}
+	return nil, nil
 }
 
 // only return error if want message redelivered, otherwise deal with it locally
@@ -256,7 +386,7 @@ func (c *cache) SnapshotRouter(ctx context.Context, err error, s *gauge.Snapshot
 	_, isUpdated := c.StationUpdated[s.Station.DataURL]
 	if !isUpdated {
 		c.StationUpdated[s.Station.DataURL] = true
-		span := c.AlgoliaWriter.StoreStation(ctx, s.Station)
+		span := c.Search.StoreStation(ctx, s.Station)
 		if err := span.Err(); err != nil {
 			// log the non-critical error but continue and do not prevent
 			// the forward flow. Note we are *not* logging the span telemetry
@@ -272,32 +402,74 @@ func (c *cache) SnapshotRouter(ctx context.Context, err error, s *gauge.Snapshot
 	urls[s.Station.DataURL] = true
 	urls[s.Station.AliasURL] = true
 	urls[s.Station.HumanURL] = true
+	// (the writers routed from a URL are replaced rather than changed on
+	// reload, so are safe to use once read)
+	isRouted := false
 	for url := range urls {
-		chs, ok := c.SnapRoute[url]
+		c.mu.RLock()
+		writers, ok := c.SnapRoute[url]
+		c.mu.RUnlock()
 		if ok {
-			for _, ch := range chs {
-				ch <- s
+			isRouted = true
+			for _, w := range writers {
+				// section writers stop on shutdown or when restarted
+				// on reload, so redeliver rather than block
+				select {
+				case w.ch <- s:
+				case <-w.stopC:
+					return errors.New("section writer stopped, redeliver " + url)
+				case <-ctx.Done():
+					return ctx.Err()
+				}
 			}
 		}
 	}
 
+	// most stations are not calibrated to any section, so only keep
+	// unrouted snapshots when explicitly asked to
+	if !isRouted && c.RejectUnrouted {
+		span := c.Topic.Reject(ctx, s, "no route")
+		if err := span.Err(); err != nil {
+			// redeliver rather than lose the snapshot
+			return err
+		}
+	}
+
 	return nil
 }
 
 func findCalibrationForStation(calibrations []river.Calibration, station gauge.Station) (cal river.Calibration, exists bool) {
 	for _, c := range calibrations {
-		if c.URL == station.DataURL {
-			exists = true
-			cal = c
-		}
-		if c.URL == station.AliasURL {
-			exists = true
-			cal = c
+		if c.URL != station.DataURL && c.URL != station.AliasURL && c.URL != station.HumanURL {
+			continue
 		}
-		if c.URL == station.HumanURL {
+		// an alias URL is shared by all measures at a station, so
+		// only use the calibration if the type and unit also match
+		if converted, ok := c.For(station.Type, station.Unit); ok {
 			exists = true
-			cal = c
+			cal = converted
 		}
 	}
+	if !exists {
+		return findDerivedInput(calibrations, station)
+	}
 	return
 }
+
+// measureKey identifies the measure a station snapshot is merged into
+func measureKey(station gauge.Station) string {
+	return station.AliasURL + " " + station.Type
+}
+
+// isRoutedToCalibration is true if a station matches a calibration URL, even
+// if it is a different type or unit of measure so cannot be used
+func isRoutedToCalibration(calibrations []river.Calibration, station gauge.Station) bool {
+	for _, c := range calibrations {
+		for _, url := range c.RouteURLs() {
+			if url == station.DataURL || url == station.AliasURL || url == station.HumanURL {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers"
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/queue"
+	"go.uber.org/goleak"
+)
+
+func TestSnapshotToRecord(t *testing.T) {
+	// verify no goroutine leaks
+	defer goleak.VerifyNoLeaks(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+	defer http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+
+	// find a calibrated section to send a snapshot to
+	var uuid, url string
+	for id, cals := range rainchasers.Calibrations {
+		if len(cals) > 0 && strings.HasPrefix(cals[0].URL, "rloi://") {
+			uuid = id
+			url = cals[0].URL
+			break
+		}
+	}
+	if uuid == "" {
+		t.Fatal("no calibrated section found")
+	}
+
+	// init daemon supervisor & context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	d := daemon.New("example")
+	topic, span := queue.NewMemory("store-test")
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	ms := NewMemoryStore()
+	c := &cache{
+		ReadyC:         make(chan struct{}),
+		Log:            d.Logger,
+		Records:        ms,
+		Search:         ms,
+		Topic:          topic,
+		StationUpdated: make(map[string]bool),
+	}
+	d.Run(ctx, c.Init)
+	d.Run(ctx, c.SubscribeToSnapshots)
+
+	select {
+	case <-c.ReadyC:
+	case <-ctx.Done():
+		t.Fatal("init timeout")
+	}
+	if _, exists := ms.River(uuid); !exists {
+		t.Fatal("section not indexed on init", uuid)
+	}
+
+	snap := &gauge.Snapshot{
+		Station: gauge.Station{
+			DataURL:  url,
+			AliasURL: url,
+			Name:     "Example Gauge",
+			Type:     "level",
+		},
+		Readings: []gauge.Reading{
+			{EventTime: time.Now(), Value: 1.23},
+		},
+	}
+
+	// a flow measure at the same station shares the alias URL but should
+	// not be used with the level calibration
+	flow := &gauge.Snapshot{
+		Station: gauge.Station{
+			DataURL:  url + "/flow",
+			AliasURL: url,
+			Name:     "Example Flow Gauge",
+			Type:     "flow",
+			Unit:     "m3/s",
+		},
+		Readings: []gauge.Reading{
+			{EventTime: time.Now().Add(-time.Minute), Value: 12.3},
+		},
+	}
+
+	// the subscription is established in the background so keep publishing
+	// the same snapshots until the record is updated
+	for {
+		if err := topic.Publish(ctx, flow).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if err := topic.Publish(ctx, snap).Err(); err != nil {
+			t.Fatal(err)
+		}
+		object, _ := ms.River(uuid)
+		if reason, _ := object["level_reason"].(string); strings.Contains(reason, "Example Gauge") {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("record not updated", object)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	if _, exists := ms.Station(url); !exists {
+		t.Error("station not indexed", url)
+	}
+
+	record, span := ms.Load(ctx, uuid)
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Measures) != 1 || len(record.Measures[0].Readings) != 1 {
+		t.Error("measure not stored", record.Measures)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	d.CloseWait()
+}
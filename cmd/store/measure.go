@@ -27,6 +27,7 @@ func (rs readingSorter) Less(i, j int) bool {
 type Record struct {
 	Section  river.Section `firestore:"section"`
 	Level    Level         `firestore:"level"`
+	Forecast Forecast      `firestore:"forecast"`
 	Measures []Measure     `firestore:"measures"`
 }
 
@@ -36,6 +37,10 @@ type Level struct {
 	ProcessedTime time.Time `firestore:"processed_time"` // time when this was processed
 	Label         string    `firestore:"label"`          // e.g. "high"
 	Reason        string    `firestore:"reason"`         // e.g. "0.98 at Hafod Wydr gauge"
+	Trend         string    `firestore:"trend"`          // e.g. "falling"
+	RatePerHour   float32   `firestore:"rate_per_hour"`  // change in gauge value per hour
+	PeakTime      time.Time `firestore:"peak_time"`      // time of last peak, zero if none
+	Estimated     bool      `firestore:"estimated"`      // approximated without a calibration
 }
 
 // Measure is a relevant river measurement time series
@@ -55,15 +60,28 @@ func (m Measure) LatestLevel() Level {
 		}
 	}
 
+	if m.Calibration.IsRainfall() {
+		return m.rainfallLevel()
+	}
+
 	// readings are always sorted by most recent first by convention
 	r := m.Readings[0]
 	v := strconv.FormatFloat(float64(r.Value), 'f', 2, 32)
-	return Level{
+	trend, rate := trendOf(m.Readings, m.Station.Unit)
+	lvl := Level{
 		EventTime:     r.EventTime,
 		ProcessedTime: m.ProcessedTime,
 		Label:         m.Calibration.LevelAt(r.Value).String(),
 		Reason:        v + " at " + m.Station.Name,
+		Trend:         trend.String(),
+		RatePerHour:   rate,
+		PeakTime:      lastPeakOf(m.Readings),
+	}
+	if m.Calibration.Estimate {
+		lvl.Label, lvl.Reason = m.estimate()
+		lvl.Estimated = true
 	}
+	return lvl
 }
 
 func merge(a []gauge.Reading, b []gauge.Reading) []gauge.Reading {
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"sync"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+	"github.com/robtuley/report"
+)
+
+// MemoryStore keeps records and search objects in memory
+type MemoryStore struct {
+	mu       sync.Mutex
+	records  map[string][]byte
+	rivers   map[string]map[string]interface{}
+	stations map[string]map[string]interface{}
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records:  make(map[string][]byte),
+		rivers:   make(map[string]map[string]interface{}),
+		stations: make(map[string]map[string]interface{}),
+	}
+}
+
+// LoadAndUpdate resets the stored record if the section has changed
+func (ms *MemoryStore) LoadAndUpdate(ctx context.Context, s river.Section) (bool, *Record, report.Span) {
+	span := report.StartSpan("memory.loadandupdate").Field("uuid", s.UUID)
+	return loadAndUpdate(ctx, ms, s, span)
+}
+
+// Load retrieves a copy of a record
+func (ms *MemoryStore) Load(ctx context.Context, uuid string) (*Record, report.Span) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.load").Field("uuid", uuid)
+
+	b, exists := ms.records[uuid]
+	if !exists {
+		return nil, span.End()
+	}
+	var record Record
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&record); err != nil {
+		return nil, span.End(err)
+	}
+	return &record, span.End()
+}
+
+// Store saves a copy of a record so later changes to it are not shared
+func (ms *MemoryStore) Store(ctx context.Context, record *Record) report.Span {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.store").Field("uuid", record.Section.UUID)
+
+	var bb bytes.Buffer
+	if err := gob.NewEncoder(&bb).Encode(record); err != nil {
+		return span.End(err)
+	}
+	ms.records[record.Section.UUID] = bb.Bytes()
+	return span.End()
+}
+
+// List retrieves the uuid of every stored record
+func (ms *MemoryStore) List(ctx context.Context) ([]string, report.Span) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.list")
+	uuids := make([]string, 0, len(ms.records))
+	for uuid := range ms.records {
+		uuids = append(uuids, uuid)
+	}
+	return uuids, span.Field("count", len(uuids)).End()
+}
+
+// Delete removes a record
+func (ms *MemoryStore) Delete(ctx context.Context, uuid string) report.Span {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.delete").Field("uuid", uuid)
+	delete(ms.records, uuid)
+	return span.End()
+}
+
+// StoreRecord indexes a river record
+func (ms *MemoryStore) StoreRecord(ctx context.Context, record *Record) report.Span {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.search.store").Field("uuid", record.Section.UUID)
+	ms.rivers[record.Section.UUID] = recordObject(record)
+	return span.End()
+}
+
+// StoreStation indexes a gauge station
+func (ms *MemoryStore) StoreStation(ctx context.Context, station gauge.Station) report.Span {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.search.store").Field("data_url", station.DataURL)
+	ms.stations[station.DataURL] = stationObject(station)
+	return span.End()
+}
+
+// ListRecords retrieves the uuid of every indexed river record
+func (ms *MemoryStore) ListRecords(ctx context.Context) ([]string, report.Span) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.search.list")
+	uuids := make([]string, 0, len(ms.rivers))
+	for uuid := range ms.rivers {
+		uuids = append(uuids, uuid)
+	}
+	return uuids, span.Field("count", len(uuids)).End()
+}
+
+// DeleteRecord removes an indexed river record
+func (ms *MemoryStore) DeleteRecord(ctx context.Context, uuid string) report.Span {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	span := report.StartSpan("memory.search.delete").Field("uuid", uuid)
+	delete(ms.rivers, uuid)
+	return span.End()
+}
+
+// River retrieves an indexed river record
+func (ms *MemoryStore) River(uuid string) (map[string]interface{}, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	object, exists := ms.rivers[uuid]
+	return object, exists
+}
+
+// Station retrieves an indexed gauge station
+func (ms *MemoryStore) Station(dataURL string) (map[string]interface{}, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	object, exists := ms.stations[dataURL]
+	return object, exists
+}
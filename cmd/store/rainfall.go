@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// rainfallLevel is the level from the rainfall accumulated at the gauge,
+// with the rate as the change in the total over the last hour
+func (m Measure) rainfallLevel() Level {
+	r := m.Readings[0]
+	total := m.Calibration.RainfallTotal(m.Readings, r.EventTime)
+	rate := total - m.Calibration.RainfallTotal(m.Readings, r.EventTime.Add(-time.Hour))
+
+	trend := river.Steady
+	switch {
+	case rate > 0:
+		trend = river.Rising
+	case rate < 0:
+		trend = river.Falling
+	}
+
+	return Level{
+		EventTime:     r.EventTime,
+		ProcessedTime: m.ProcessedTime,
+		Label:         m.Calibration.LevelAt(total).String(),
+		Reason:        format(total) + "mm rain in " + windowDescription(m.Calibration.RainfallWindow()) + " at " + m.Station.Name,
+		Trend:         trend.String(),
+		RatePerHour:   rate,
+	}
+}
+
+// windowDescription formats a window such as 12h or 1h30m
+func windowDescription(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
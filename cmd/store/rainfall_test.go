@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func TestRainfallLevel(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:00:00Z")
+	m := Measure{
+		Station: gauge.Station{Name: "Capel Curig", Type: "rainfall", Unit: "mm"},
+		Calibration: river.Calibration{
+			Type:   river.RainfallType,
+			Window: 2 * time.Hour,
+			Minimum: map[string]float32{
+				river.Scrape.String(): 5,
+				river.Low.String():    10,
+			},
+		},
+		Readings: readingsEvery15Min(start, 0, 1, 0, 0.5, 1.5, 2, 2, 1),
+	}
+
+	l := m.LevelAt(start.Add(2 * time.Hour))
+	if l.Label != river.Scrape.String() || l.Reason != "8.00mm rain in 2h at Capel Curig" {
+		t.Error("Unexpected rainfall level", l)
+	}
+	if l.Trend != river.Rising.String() || l.RatePerHour != 6.5 {
+		t.Error("Unexpected rainfall trend", l.Trend, l.RatePerHour)
+	}
+	if f := m.ForecastAt(start.Add(2 * time.Hour)); f.Label != "" {
+		t.Error("Rainfall should not forecast", f)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/river"
+	"github.com/robtuley/report"
+)
+
+// reconcile removes the stored records and search objects of any section no
+// longer in the content, so deleted sections stop being served. Unless
+// deletion is enabled this is a dry run that only reports what would be
+// removed.
+func (c *cache) reconcile(ctx context.Context, d *daemon.Supervisor, content river.Content) error {
+	// an empty catalogue is far more likely to be a content mistake
+	// than a deliberate removal of every section
+	if len(content.Sections) == 0 {
+		c.Log.Action("reconcile.skipped", report.Data{
+			"reason": "content has no sections",
+		})
+		return nil
+	}
+
+	wanted := make(map[string]bool, len(content.Sections))
+	for _, s := range content.Sections {
+		wanted[s.UUID] = true
+	}
+
+	records, span := c.Records.List(ctx)
+	d.Trace(span)
+	if err := span.Err(); err != nil {
+		return err
+	}
+	indexed, span := c.Search.ListRecords(ctx)
+	d.Trace(span)
+	if err := span.Err(); err != nil {
+		return err
+	}
+
+	stale := func(uuids []string) []string {
+		var s []string
+		for _, uuid := range uuids {
+			if !wanted[uuid] {
+				s = append(s, uuid)
+			}
+		}
+		sort.Strings(s)
+		return s
+	}
+	staleRecords := stale(records)
+	staleIndexed := stale(indexed)
+
+	for _, uuid := range staleRecords {
+		if !c.ReconcileDelete {
+			c.Log.Info("reconcile.stale", report.Data{
+				"uuid":  uuid,
+				"store": "records",
+			})
+			continue
+		}
+		span := c.Records.Delete(ctx, uuid)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
+		}
+	}
+	for _, uuid := range staleIndexed {
+		if !c.ReconcileDelete {
+			c.Log.Info("reconcile.stale", report.Data{
+				"uuid":  uuid,
+				"store": "search",
+			})
+			continue
+		}
+		span := c.Search.DeleteRecord(ctx, uuid)
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
+		}
+	}
+
+	c.Log.Info("reconcile.report", report.Data{
+		"sections":      len(content.Sections),
+		"records":       len(records),
+		"indexed":       len(indexed),
+		"stale_records": len(staleRecords),
+		"stale_indexed": len(staleIndexed),
+		"dry_run":       !c.ReconcileDelete,
+	})
+	return nil
+}
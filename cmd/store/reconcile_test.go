@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	d := daemon.New("example")
+	defer d.CloseWait()
+
+	ms := NewMemoryStore()
+	for _, uuid := range []string{"kept", "removed"} {
+		record := &Record{Section: river.Section{UUID: uuid}}
+		ms.Store(ctx, record)
+		ms.StoreRecord(ctx, record)
+	}
+	// a search object can outlive its record
+	ms.StoreRecord(ctx, &Record{Section: river.Section{UUID: "orphan"}})
+
+	c := &cache{Log: d.Logger, Records: ms, Search: ms}
+	content := river.Content{
+		Sections: []river.Section{{UUID: "kept"}},
+	}
+	list := func() ([]string, []string) {
+		records, _ := ms.List(ctx)
+		indexed, _ := ms.ListRecords(ctx)
+		sort.Strings(records)
+		sort.Strings(indexed)
+		return records, indexed
+	}
+
+	// a dry run only reports
+	if err := c.reconcile(ctx, d, content); err != nil {
+		t.Fatal(err)
+	}
+	records, indexed := list()
+	if len(records) != 2 || len(indexed) != 3 {
+		t.Error("dry run deleted records", records, indexed)
+	}
+
+	// nothing is deleted if the content is empty
+	c.ReconcileDelete = true
+	if err := c.reconcile(ctx, d, river.Content{}); err != nil {
+		t.Fatal(err)
+	}
+	records, indexed = list()
+	if len(records) != 2 || len(indexed) != 3 {
+		t.Error("empty content deleted records", records, indexed)
+	}
+
+	if err := c.reconcile(ctx, d, content); err != nil {
+		t.Fatal(err)
+	}
+	records, indexed = list()
+	if len(records) != 1 || records[0] != "kept" {
+		t.Error("stale records not deleted", records)
+	}
+	if len(indexed) != 1 || indexed[0] != "kept" {
+		t.Error("stale search records not deleted", indexed)
+	}
+}
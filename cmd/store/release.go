@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// releaseLocation is the local time releases are described in, as they are
+// published by the dam operators (falling back to UTC if it is unknown)
+var releaseLocation = func() *time.Location {
+	zone, err := time.LoadLocation(river.ReleaseZone)
+	if err != nil {
+		return time.UTC
+	}
+	return zone
+}()
+
+// releaseLevel overrides the level of a section while a scheduled release
+// from its dam is running, and otherwise supplements the level from any
+// gauges with a forecast of the next release
+func releaseLevel(cal river.Calendar, lvl Level, forecast Forecast, now time.Time) (Level, Forecast) {
+	if len(cal.Releases) == 0 {
+		return lvl, forecast
+	}
+
+	if r, running := cal.At(now); running {
+		return Level{
+			EventTime:     r.StartTime(),
+			ProcessedTime: now,
+			Label:         r.Level().String(),
+			Reason:        "Scheduled release" + flowDescription(r) + " until " + r.EndTime().In(releaseLocation).Format("Mon 15:04 MST") + calendarDescription(cal),
+		}, Forecast{
+			Label:        river.Empty.String(),
+			ExpectedTime: r.EndTime(),
+			Reason:       "release expected to end in " + approxDuration(r.EndTime().Sub(now)),
+		}
+	}
+
+	next, isScheduled := cal.Next(now)
+
+	// without an opinion from a gauge the section is only
+	// expected to run during releases
+	if lvl.Label == river.Unknown.String() {
+		reason := "No release scheduled"
+		if isScheduled {
+			reason = "No release until " + next.StartTime().In(releaseLocation).Format("Mon 2 Jan 15:04 MST")
+		}
+		lvl = Level{
+			EventTime:     now,
+			ProcessedTime: now,
+			Label:         river.Empty.String(),
+			Reason:        reason + calendarDescription(cal),
+		}
+	}
+
+	if isScheduled && next.StartTime().Sub(now) <= forecastHorizon {
+		if forecast.Label == "" || next.StartTime().Before(forecast.ExpectedTime) {
+			forecast = Forecast{
+				Label:        next.Level().String(),
+				ExpectedTime: next.StartTime(),
+				Reason:       "release" + flowDescription(next) + " expected to start in " + approxDuration(next.StartTime().Sub(now)),
+			}
+		}
+	}
+	return lvl, forecast
+}
+
+func flowDescription(r river.Release) string {
+	if r.Flow <= 0 {
+		return ""
+	}
+	return " of " + strconv.FormatFloat(float64(r.Flow), 'f', -1, 32) + "m3/s"
+}
+
+func calendarDescription(cal river.Calendar) string {
+	if cal.Description == "" {
+		return ""
+	}
+	return " (" + cal.Description + ")"
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+func TestReleaseLevel(t *testing.T) {
+	day := time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC)
+	cal := river.Calendar{
+		Description: "Example Dam",
+		Releases: []river.Release{
+			{Start: day.Add(8 * time.Hour).Unix(), End: day.Add(15 * time.Hour).Unix(), Flow: 12.5},
+		},
+	}
+	unknown := Level{Label: river.Unknown.String(), Reason: "Not yet calibrated against nearby gauges"}
+
+	// overrides the gauge during the release
+	gauge := Level{Label: river.Low.String(), Reason: "0.50 at Example Gauge"}
+	l, f := releaseLevel(cal, gauge, Forecast{}, day.Add(10*time.Hour))
+	if l.Label != river.Medium.String() || l.Reason != "Scheduled release of 12.5m3/s until Sat 16:00 BST (Example Dam)" {
+		t.Error("Unexpected level during release", l)
+	}
+	if f.Label != river.Empty.String() || !f.ExpectedTime.Equal(day.Add(15*time.Hour)) {
+		t.Error("Unexpected forecast during release", f)
+	}
+
+	// supplements the gauge with the next release
+	l, f = releaseLevel(cal, gauge, Forecast{}, day.Add(5*time.Hour))
+	if l != gauge {
+		t.Error("Gauge level not kept before release", l)
+	}
+	if f.Label != river.Medium.String() || f.Reason != "release of 12.5m3/s expected to start in ~3h" {
+		t.Error("Unexpected forecast before release", f)
+	}
+
+	// is empty without a gauge, describing the next release in local time
+	l, _ = releaseLevel(cal, unknown, Forecast{}, day.Add(5*time.Hour))
+	if l.Label != river.Empty.String() || l.Reason != "No release until Sat 4 Jul 09:00 BST (Example Dam)" {
+		t.Error("Unexpected level before release", l)
+	}
+	l, f = releaseLevel(cal, unknown, Forecast{}, day.Add(16*time.Hour))
+	if l.Label != river.Empty.String() || l.Reason != "No release scheduled (Example Dam)" {
+		t.Error("Unexpected level after release", l)
+	}
+	if f.Label != "" {
+		t.Error("Unexpected forecast after release", f)
+	}
+
+	if l, _ := releaseLevel(river.Calendar{}, unknown, Forecast{}, day); l != unknown {
+		t.Error("Level changed without a calendar", l)
+	}
+}
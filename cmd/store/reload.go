@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/robtuley/rainchasers"
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+	"github.com/robtuley/report"
+)
+
+// sectionWriter is the goroutines updating a single section, which are
+// restarted whenever the content for the section changes
+type sectionWriter struct {
+	Content sectionContent
+
+	ch     chan *gauge.Snapshot // nil if not listening to snapshots
+	stopC  <-chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// sectionContent is everything in the content about a single section
+type sectionContent struct {
+	Section      river.Section
+	Calibrations []river.Calibration
+	Calendar     river.Calendar
+}
+
+// run launches a goroutine for the section
+func (w *sectionWriter) run(ctx context.Context, d *daemon.Supervisor, fn func(ctx context.Context, d *daemon.Supervisor) error) {
+	w.wg.Add(1)
+	d.Run(ctx, func(ctx context.Context, d *daemon.Supervisor) error {
+		defer w.wg.Done()
+		return fn(ctx, d)
+	})
+}
+
+// ReloadContent applies content changes to the running section writers
+// once the initial content has been applied
+func (c *cache) ReloadContent(ctx context.Context, d *daemon.Supervisor) error {
+	// wait for init
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-c.ReadyC:
+	}
+
+	// only the latest content matters if reloads arrive faster than they
+	// can be applied
+	reloadC := make(chan river.Content, 1)
+	c.Content.OnReload(func(content river.Content) {
+		select {
+		case <-reloadC:
+		default:
+		}
+		reloadC <- content
+	})
+	d.Run(ctx, func(ctx context.Context, d *daemon.Supervisor) error {
+		rainchasers.WatchContent(ctx, c.Content, d.Logger)
+		return nil
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case content := <-reloadC:
+			if err := c.applyContent(ctx, d, content); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// applyContent brings the section writers in line with the content,
+// restarting the writers of any section with changed content and stopping
+// those of removed sections
+func (c *cache) applyContent(ctx context.Context, d *daemon.Supervisor, content river.Content) error {
+	if c.writers == nil {
+		c.writers = make(map[string]*sectionWriter)
+	}
+
+	// update catalogue in storage (rate limited if remote)
+	var tickC <-chan time.Time
+	if c.UpdateEvery > 0 {
+		ticker := time.NewTicker(c.UpdateEvery)
+		defer ticker.Stop()
+		tickC = ticker.C
+	}
+
+	wanted := make(map[string]bool)
+	nStarted := 0
+updateLoop:
+	for _, s := range content.Sections {
+		wanted[s.UUID] = true
+		calibrations, isCalibrated := content.Calibrations[s.UUID]
+		calendar, hasReleases := content.Releases[s.UUID]
+
+		// nothing to do if the content is unchanged (compared directly as
+		// a checksum of the calibration threshold maps is not stable)
+		sc := sectionContent{s, calibrations, calendar}
+		if w, exists := c.writers[s.UUID]; exists && reflect.DeepEqual(w.Content, sc) {
+			continue updateLoop
+		}
+
+		// stop any writer using the old content before the record is
+		// reloaded, so there is only ever one writer per record (its
+		// routes refuse snapshots until replaced, so they are redelivered)
+		old := c.stopWriter(s.UUID)
+
+		// get stored info for the section
+		// (& update if necessary and in search if changed)
+		hasChanged, record, span := c.Records.LoadAndUpdate(ctx, s)
+		if hasChanged {
+			aSpan := c.Search.StoreRecord(ctx, record)
+			span = span.FollowedBy(aSpan)
+		}
+		d.Trace(span)
+		if err := span.Err(); err != nil {
+			return err
+		}
+
+		wCtx, cancel := context.WithCancel(context.Background())
+		w := &sectionWriter{
+			Content: sc,
+			stopC:   wCtx.Done(),
+			cancel:  cancel,
+		}
+
+		// if calibration or a release calendar exists then launch
+		// goroutine to listen to snapshots & releases and update river
+		if isCalibrated || hasReleases {
+			w.ch = make(chan *gauge.Snapshot)
+			w.run(wCtx, d, c.CreateSnapshotsWriter(*record, calibrations, calendar, w.ch))
+		}
+
+		c.writers[s.UUID] = w
+		c.reroute(old, w)
+		nStarted++
+
+		if tickC == nil {
+			continue updateLoop
+		}
+		select {
+		case <-ctx.Done():
+			break updateLoop
+		case <-tickC:
+		}
+	}
+
+	// stop the writers of any sections no longer in the content
+	nStopped := 0
+	if ctx.Err() == nil {
+		for uuid := range c.writers {
+			if !wanted[uuid] {
+				c.reroute(c.stopWriter(uuid), nil)
+				nStopped++
+			}
+		}
+	}
+
+	// only remove the records of removed sections once their writers have
+	// stopped, so a writer cannot re-create one
+	if ctx.Err() == nil {
+		if err := c.reconcile(ctx, d, content); err != nil {
+			return err
+		}
+	}
+
+	c.Log.Info("content.applied", report.Data{
+		"sections": len(content.Sections),
+		"started":  nStarted,
+		"stopped":  nStopped,
+	})
+	return nil
+}
+
+// stopWriter stops the goroutines of a section, waiting for them to finish,
+// and returns the stopped writer (nil if there was none) so its routes can
+// be replaced
+func (c *cache) stopWriter(uuid string) *sectionWriter {
+	w, exists := c.writers[uuid]
+	if !exists {
+		return nil
+	}
+	delete(c.writers, uuid)
+
+	w.cancel()
+	w.wg.Wait()
+	return w
+}
+
+// reroute replaces the routes from station URL to a section writer with
+// the routes to its replacement, either of which can be nil, in a single
+// change to the routing table so the section is never left without a route
+func (c *cache) reroute(old *sectionWriter, w *sectionWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.SnapRoute == nil {
+		c.SnapRoute = make(map[string][]*sectionWriter)
+	}
+
+	// the slices are replaced rather than changed, as the router uses
+	// them once read without holding the lock
+	for _, url := range old.routeURLs() {
+		var writers []*sectionWriter
+		for _, routed := range c.SnapRoute[url] {
+			if routed != old {
+				writers = append(writers, routed)
+			}
+		}
+		if len(writers) == 0 {
+			delete(c.SnapRoute, url)
+			continue
+		}
+		c.SnapRoute[url] = writers
+	}
+	for _, url := range w.routeURLs() {
+		routed := c.SnapRoute[url]
+		writers := make([]*sectionWriter, len(routed), len(routed)+1)
+		copy(writers, routed)
+		c.SnapRoute[url] = append(writers, w)
+	}
+}
+
+// routeURLs are the station URLs to route snapshots from to the writer,
+// once per URL as a derived gauge can share inputs with other calibrations
+func (w *sectionWriter) routeURLs() []string {
+	if w == nil || w.ch == nil {
+		return nil
+	}
+
+	var urls []string
+	routed := make(map[string]bool)
+	for _, m := range w.Content.Calibrations {
+		for _, url := range m.RouteURLs() {
+			if !routed[url] {
+				routed[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls
+}
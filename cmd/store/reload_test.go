@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+	"go.uber.org/goleak"
+)
+
+func TestReloadCalibrations(t *testing.T) {
+	// verify no goroutine leaks
+	defer goleak.VerifyNoLeaks(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+	defer http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+
+	const url = "rloi://2003"
+	section := river.Section{
+		UUID:        "ccd035ae-9b6c-4bae-b0d0-bfc016d1b8a0",
+		Slug:        "vyrnwy-lake-vyrnwy-pont-llogel",
+		SectionName: "Lake Vyrnwy to Pont Llogel",
+		RiverName:   "Vyrnwy",
+	}
+	calibrated := func(minimum map[string]float32) river.Content {
+		return river.Content{
+			Sections: []river.Section{section},
+			Calibrations: map[string][]river.Calibration{
+				section.UUID: {{URL: url, Minimum: minimum}},
+			},
+		}
+	}
+	label := func(ms *MemoryStore) string {
+		object, _ := ms.River(section.UUID)
+		label, _ := object["level_label"].(string)
+		return label
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	d := daemon.New("example")
+	ms := NewMemoryStore()
+	c := &cache{
+		ReadyC:         make(chan struct{}),
+		Log:            d.Logger,
+		Records:        ms,
+		Search:         ms,
+		Content:        river.NewStaticLoader(calibrated(map[string]float32{"low": 1.0})),
+		StationUpdated: make(map[string]bool),
+	}
+	d.Run(ctx, c.Init)
+	select {
+	case <-c.ReadyC:
+	case <-ctx.Done():
+		t.Fatal("init timeout")
+	}
+
+	snap := &gauge.Snapshot{
+		Station: gauge.Station{
+			DataURL:  url,
+			AliasURL: url,
+			Name:     "Example Gauge",
+			Type:     "level",
+		},
+		Readings: []gauge.Reading{
+			{EventTime: time.Now(), Value: 1.23},
+		},
+	}
+	if err := c.SnapshotRouter(ctx, nil, snap); err != nil {
+		t.Fatal(err)
+	}
+	for label(ms) != river.Low.String() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("level not updated from snapshot", label(ms))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// a changed calibration restarts the writer and re-evaluates the
+	// level from the stored readings without waiting for a snapshot
+	if err := c.applyContent(ctx, d, calibrated(map[string]float32{"low": 1.0, "medium": 1.2})); err != nil {
+		t.Fatal(err)
+	}
+	for label(ms) != river.Medium.String() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("level not re-evaluated on reload", label(ms))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if err := c.SnapshotRouter(ctx, nil, snap); err != nil {
+		t.Error("snapshot not routed to restarted writer", err)
+	}
+
+	// a removed section stops its writer
+	if err := c.applyContent(ctx, d, river.Content{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.writers) != 0 || len(c.SnapRoute) != 0 {
+		t.Error("writer not stopped", c.writers, c.SnapRoute)
+	}
+
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	d.CloseWait()
+}
+
+func TestRerouteReplacesWriter(t *testing.T) {
+	const url = "rloi://2003"
+	writer := func() *sectionWriter {
+		return &sectionWriter{
+			Content: sectionContent{
+				Calibrations: []river.Calibration{{URL: url}, {URL: url}},
+			},
+			ch: make(chan *gauge.Snapshot),
+		}
+	}
+	c := &cache{}
+
+	// sections can share a station, each routed once
+	a, b := writer(), writer()
+	c.reroute(nil, a)
+	c.reroute(nil, b)
+	if routed := c.SnapRoute[url]; len(routed) != 2 || routed[0] != a || routed[1] != b {
+		t.Fatal("expected both writers routed once", routed)
+	}
+
+	// a restarted writer takes over the routes of the old one, leaving
+	// the other section in place
+	restarted := writer()
+	c.reroute(a, restarted)
+	if routed := c.SnapRoute[url]; len(routed) != 2 || routed[0] != b || routed[1] != restarted {
+		t.Error("expected restarted writer to replace the old", routed)
+	}
+
+	// the route is removed with the last writer
+	c.reroute(b, nil)
+	c.reroute(restarted, nil)
+	if len(c.SnapRoute) != 0 {
+		t.Error("expected no routes", c.SnapRoute)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+	"github.com/robtuley/report"
+)
+
+// RecordStore persists river records
+type RecordStore interface {
+	// Load retrieves a record, or nil if it does not exist
+	Load(ctx context.Context, uuid string) (*Record, report.Span)
+	// Store saves a record
+	Store(ctx context.Context, record *Record) report.Span
+	// LoadAndUpdate retrieves a record, resetting it if the section has changed
+	LoadAndUpdate(ctx context.Context, s river.Section) (hasChanged bool, record *Record, traceSpan report.Span)
+	// List retrieves the uuid of every stored record
+	List(ctx context.Context) ([]string, report.Span)
+	// Delete removes a record, if it exists
+	Delete(ctx context.Context, uuid string) report.Span
+}
+
+// SearchSink indexes river records and stations for search
+type SearchSink interface {
+	// StoreRecord indexes a river record
+	StoreRecord(ctx context.Context, record *Record) report.Span
+	// StoreStation indexes a gauge station
+	StoreStation(ctx context.Context, station gauge.Station) report.Span
+	// ListRecords retrieves the uuid of every indexed river record
+	ListRecords(ctx context.Context) ([]string, report.Span)
+	// DeleteRecord removes an indexed river record, if it exists
+	DeleteRecord(ctx context.Context, uuid string) report.Span
+}
+
+// loadAndUpdate loads the record for a section from a store and if the river
+// definition has changed writes a new record with no gauge readings or state
+func loadAndUpdate(ctx context.Context, rs RecordStore, s river.Section, span report.Span) (bool, *Record, report.Span) {
+	// get existing river data to check against
+	record, sp := rs.Load(ctx, s.UUID)
+	span = span.Child(sp)
+	if err := span.Err(); err != nil {
+		return false, record, span.End()
+	}
+
+	// if river has not changed, do nothing
+	if record != nil {
+		if checksum(s) == checksum(record.Section) {
+			return false, record, span.End()
+		}
+	}
+
+	// write new data with no snapshot gauge readings or state only if river
+	// definition has changed
+	new := Record{
+		Section: s,
+		Level: Level{
+			Label:  river.Unknown.String(),
+			Reason: "Not yet calibrated against nearby gauges",
+		},
+		Measures: make([]Measure, 0),
+	}
+	sp = rs.Store(ctx, &new)
+	span = span.Child(sp)
+	return true, &new, span.End()
+}
+
+// recordObject is the search index representation of a river record
+func recordObject(record *Record) map[string]interface{} {
+	s := record.Section
+	l := record.Level
+	f := record.Forecast
+
+	// always an array so the app can filter on old slugs
+	aliases := s.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	return map[string]interface{}{
+		"objectID":      s.UUID,
+		"slug":          s.Slug,
+		"aliases":       aliases,
+		"section":       s.SectionName,
+		"river":         s.RiverName,
+		"grade":         s.Grade.Human,
+		"grade_numeric": s.Grade.Average,
+		"desc":          s.Description,
+		"km":            s.KM,
+		"_geoloc": map[string]float32{
+			"lat": s.Putin.Lat,
+			"lng": s.Putin.Lng,
+		},
+		"level_label":     l.Label,
+		"level_reason":    l.Reason,
+		"level_timestamp": l.EventTime,
+		"level_trend":     l.Trend,
+		"level_rate":      l.RatePerHour,
+		"level_peak":      l.PeakTime,
+		"level_estimated": l.Estimated,
+		"forecast_label":  f.Label,
+		"forecast_time":   f.ExpectedTime,
+		"forecast_reason": f.Reason,
+	}
+}
+
+// stationObject is the search index representation of a gauge station
+func stationObject(station gauge.Station) map[string]interface{} {
+	obj := map[string]interface{}{
+		"objectID":  station.DataURL,
+		"alias_url": station.AliasURL,
+		"human_url": station.HumanURL,
+		"name":      station.Name,
+		"river":     station.RiverName,
+		"type":      station.Type,
+		"_geoloc": map[string]float32{
+			"lat": station.Lat,
+			"lng": station.Lg,
+		},
+	}
+
+	// optional statistics are only indexed where known
+	for k, v := range map[string]*float32{
+		"datum":          station.Datum,
+		"catchment_area": station.CatchmentArea,
+		"lowest":         station.Lowest,
+		"low":            station.Low,
+		"high":           station.High,
+		"highest":        station.Highest,
+		"mean":           station.Mean,
+	} {
+		if v != nil {
+			obj[k] = *v
+		}
+	}
+	if station.CatchmentName != "" {
+		obj["catchment"] = station.CatchmentName
+	}
+	if station.InfoURL != "" {
+		obj["info_url"] = station.InfoURL
+	}
+	return obj
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+const (
+	// trendWindow is the period before the latest reading used to
+	// calculate the current rate of change
+	trendWindow = 3 * time.Hour
+	// peakWindow is the period either side of a reading that it must
+	// be the highest value within to count as a peak
+	peakWindow = 3 * time.Hour
+)
+
+// steadyFloors are the smallest changes over the trend window that count
+// as a rise or fall for values close to zero, for each thing measured
+var steadyFloors = []struct {
+	Change float32
+	Unit   string
+}{
+	{0.01, "m"},   // 1cm of level
+	{0.1, "m3/s"}, // 0.1 cumecs of flow
+}
+
+// steadyFloor is the smallest change in a unit that counts as a rise or
+// fall, assuming a level in metres if the unit is not known
+func steadyFloor(unit string) float64 {
+	for _, f := range steadyFloors {
+		if change, ok := river.ConvertUnit(f.Change, f.Unit, unit); ok {
+			return float64(change)
+		}
+	}
+	return 0.01
+}
+
+// trendOf calculates the direction and rate of change per hour of readings
+// in a unit sorted most recent first
+//
+// The rate is the least squares gradient of the readings within the trend
+// window. A change over the window of less than 2% of the current value (or
+// 1cm of level or 0.1m3/s of flow for values close to zero) is considered
+// steady.
+func trendOf(readings []gauge.Reading, unit string) (river.Trend, float32) {
+	if len(readings) < 2 {
+		return river.Steady, 0
+	}
+
+	latest := readings[0]
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, r := range readings {
+		age := latest.EventTime.Sub(r.EventTime)
+		if age > trendWindow {
+			break
+		}
+		x := -age.Hours()
+		y := float64(r.Value)
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return river.Steady, 0
+	}
+	rate := (n*sumXY - sumX*sumY) / denominator
+
+	threshold := math.Max(steadyFloor(unit), 0.02*math.Abs(float64(latest.Value)))
+	change := rate * trendWindow.Hours()
+	switch {
+	case change > threshold:
+		return river.Rising, float32(rate)
+	case change < -threshold:
+		return river.Falling, float32(rate)
+	}
+	return river.Steady, float32(rate)
+}
+
+// lastPeakOf finds the time of the most recent peak in readings sorted most
+// recent first, or a zero time if there is none
+//
+// A peak is a reading strictly higher than every other reading within the
+// peak window either side of it, and that has since started to drop.
+func lastPeakOf(readings []gauge.Reading) time.Time {
+nextReading:
+	for i := 1; i < len(readings); i++ {
+		peak := readings[i]
+		if readings[i-1].Value >= peak.Value {
+			continue
+		}
+		for j, r := range readings {
+			if j == i {
+				continue
+			}
+			δ := r.EventTime.Sub(peak.EventTime)
+			if δ > peakWindow || δ < -peakWindow {
+				continue
+			}
+			if r.Value >= peak.Value {
+				continue nextReading
+			}
+		}
+		return peak.EventTime
+	}
+	return time.Time{}
+}
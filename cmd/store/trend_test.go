@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/rainchasers/internal/river"
+)
+
+// readingsEvery15Min creates readings from values listed oldest first,
+// returned sorted most recent first as stored on a measure
+func readingsEvery15Min(start time.Time, values ...float32) []gauge.Reading {
+	var readings []gauge.Reading
+	for i, v := range values {
+		readings = append(readings, gauge.Reading{
+			EventTime: start.Add(time.Duration(i) * 15 * time.Minute),
+			Value:     v,
+		})
+	}
+	return merge(nil, readings)
+}
+
+func TestTrendOf(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+
+	rising := readingsEvery15Min(start, 1.0, 1.1, 1.2, 1.3, 1.4)
+	if trend, rate := trendOf(rising, "m"); trend != river.Rising || rate < 0.39 || rate > 0.41 {
+		t.Error("rising readings are", trend, rate)
+	}
+
+	falling := readingsEvery15Min(start, 1.4, 1.3, 1.2, 1.1, 1.0)
+	if trend, rate := trendOf(falling, "m"); trend != river.Falling || rate > -0.39 || rate < -0.41 {
+		t.Error("falling readings are", trend, rate)
+	}
+
+	steady := readingsEvery15Min(start, 1.0, 1.001, 0.999, 1.0, 1.001)
+	if trend, _ := trendOf(steady, "m"); trend != river.Steady {
+		t.Error("steady readings are", trend)
+	}
+
+	if trend, rate := trendOf(steady[:1], "m"); trend != river.Steady || rate != 0 {
+		t.Error("single reading is", trend, rate)
+	}
+}
+
+func TestTrendOfFlow(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+
+	rising := readingsEvery15Min(start, 0.5, 1.0, 1.5, 2.0, 2.5)
+	if trend, rate := trendOf(rising, "m3/s"); trend != river.Rising || rate < 1.99 || rate > 2.01 {
+		t.Error("rising flow is", trend, rate)
+	}
+
+	// a few litres a second is noise on a low flow, not a rise
+	low := readingsEvery15Min(start, 2, 3, 2, 4, 5)
+	if trend, _ := trendOf(low, "l/s"); trend != river.Steady {
+		t.Error("low flow in l/s is", trend)
+	}
+	if trend, _ := trendOf(readingsEvery15Min(start, 0.002, 0.003, 0.002, 0.004, 0.005), "m3/s"); trend != river.Steady {
+		t.Error("low flow in m3/s is", trend)
+	}
+}
+
+func TestTrendOfIgnoresReadingsOutsideWindow(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	readings := merge(
+		[]gauge.Reading{{EventTime: start.Add(-24 * time.Hour), Value: 10.0}},
+		readingsEvery15Min(start, 1.0, 1.0, 1.0, 1.0, 1.0),
+	)
+	if trend, _ := trendOf(readings, "m"); trend != river.Steady {
+		t.Error("old readings included in trend", trend)
+	}
+}
+
+func TestLastPeakOf(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+
+	readings := readingsEvery15Min(start, 1.0, 1.5, 2.0, 1.8, 1.6, 1.4)
+	if peak := lastPeakOf(readings); !peak.Equal(start.Add(30 * time.Minute)) {
+		t.Error("peak found at", peak)
+	}
+
+	// still rising so no peak yet
+	readings = readingsEvery15Min(start, 1.0, 1.5, 2.0)
+	if peak := lastPeakOf(readings); !peak.IsZero() {
+		t.Error("rising readings have peak", peak)
+	}
+
+	// flat readings have no peak
+	readings = readingsEvery15Min(start, 1.0, 1.0, 1.0)
+	if peak := lastPeakOf(readings); !peak.IsZero() {
+		t.Error("flat readings have peak", peak)
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/robtuley/rainchasers"
@@ -14,7 +17,7 @@ import (
 const port = ":8080"
 
 var sectionT *template.Template
-var sectionM map[string]river.Section
+var sectionC = river.NewCatalogue(river.Content{})
 var logger *report.Logger
 var version string
 
@@ -30,14 +33,24 @@ func init() {
 	f2 := filepath.Join("static", "badges.html")
 	f3 := filepath.Join("static", "home.html")
 	sectionT = template.Must(template.ParseFiles(f1, f2, f3))
-
-	sectionM = make(map[string]river.Section, len(rainchasers.Sections))
-	for _, s := range rainchasers.Sections {
-		sectionM["/"+s.Slug] = s
-	}
 }
 
+// Responds to environment variables:
+//   CONTENT_DIR (no default, blank for the content compiled into the binary)
 func main() {
+	// load river content, reloading as it changes
+	content, err := rainchasers.LoadContent(os.Getenv("CONTENT_DIR"), logger)
+	if err != nil {
+		<-logger.Action("content.invalid", report.Data{
+			"error": err.Error(),
+		})
+		logger.Close()
+		os.Exit(1)
+	}
+	sectionC.Update(content.Content())
+	content.OnReload(sectionC.Update)
+	go rainchasers.WatchContent(context.Background(), content, logger)
+
 	// setup routes
 	fs := http.FileServer(http.Dir("./static"))
 	http.Handle("/s/", http.StripPrefix("/s/", fs))
@@ -49,7 +62,7 @@ func main() {
 		"version": version,
 		"port":    port,
 	})
-	err := http.ListenAndServe(port, nil)
+	err = http.ListenAndServe(port, nil)
 	if err != nil {
 		logger.Action("http.stop", report.Data{
 			"version": version,
@@ -60,8 +73,8 @@ func main() {
 
 func serveTemplate(w http.ResponseWriter, r *http.Request) {
 	// try to serve a section
-	s, exists := sectionM[r.URL.Path]
-	if exists {
+	s, isAlias, exists := sectionC.Find(strings.TrimPrefix(r.URL.Path, "/"))
+	if exists && !isAlias {
 		logger.Info("http.response", report.Data{
 			"status":  200,
 			"path":    r.URL.Path,
@@ -71,6 +84,17 @@ func serveTemplate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// permanently redirect the old slug of a renamed section
+	if exists {
+		logger.Info("http.response", report.Data{
+			"status":  301,
+			"path":    r.URL.Path,
+			"section": s.UUID,
+		})
+		http.Redirect(w, r, "/"+s.Slug, 301)
+		return
+	}
+
 	// redirect to home
 	if r.URL.Path != "/" {
 		logger.Info("http.response", report.Data{
@@ -88,6 +112,6 @@ func serveTemplate(w http.ResponseWriter, r *http.Request) {
 	})
 	sectionT.ExecuteTemplate(w, "home", homePage{
 		Version:  version,
-		Sections: rainchasers.Sections,
+		Sections: sectionC.All(),
 	})
 }
@@ -0,0 +1,82 @@
+package rainchasers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/river"
+	"github.com/robtuley/report"
+)
+
+// ContentReloadEvery is how often a content directory is checked for changes
+const ContentReloadEvery = time.Minute
+
+// Compiled is the content generated into this package by go generate
+func Compiled() river.Content {
+	return river.Content{
+		Sections:     Sections,
+		Calibrations: Calibrations,
+		Releases:     Releases,
+	}
+}
+
+// LoadContent loads the content from a directory at runtime, or provides
+// the compiled content if the directory is blank
+func LoadContent(dir string, log *report.Logger) (*river.Loader, error) {
+	if dir == "" {
+		return river.NewStaticLoader(Compiled()), nil
+	}
+	l, problems, err := river.NewLoader(dir)
+	logProblems(log, problems)
+	return l, err
+}
+
+// WatchContent reloads the content as the directory changes until the
+// context is cancelled, keeping the current content if an update is invalid
+func WatchContent(ctx context.Context, l *river.Loader, log *report.Logger) {
+	ticker := time.NewTicker(ContentReloadEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		hasChanged, problems, err := l.Reload()
+		if err != nil {
+			logProblems(log, problems)
+			log.Action("content.invalid", report.Data{
+				"error": err.Error(),
+			})
+			continue
+		}
+		if hasChanged {
+			logProblems(log, problems)
+			log.Info("content.reloaded", report.Data{
+				"sections": len(l.Content().Sections),
+			})
+		}
+	}
+}
+
+func logProblems(log *report.Logger, problems map[string][]river.Problem) {
+	var files []string
+	for fn := range problems {
+		files = append(files, fn)
+	}
+	sort.Strings(files)
+
+	for _, fn := range files {
+		for _, p := range problems[fn] {
+			log.Info("content.problem", report.Data{
+				"file":    fn,
+				"field":   p.Field,
+				"message": p.Message,
+				"fatal":   p.IsFatal,
+			})
+		}
+	}
+}
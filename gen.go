@@ -7,79 +7,49 @@
 package main
 
 import (
-	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
+	"sort"
+	"strconv"
 	"text/template"
-	"time"
 
 	"github.com/robtuley/rainchasers/internal/river"
-	"gopkg.in/yaml.v2"
 )
 
-type YamlCalibration struct {
-	URL         string   `yaml:"data_url"`
-	Description string   `yaml:"desc"`
-	Scrape      *float32 `yaml:"scrape,omitempty"`
-	Low         *float32 `yaml:"low,omitempty"`
-	Medium      *float32 `yaml:"medium,omitempty"`
-	High        *float32 `yaml:"high,omitempty"`
-	Huge        *float32 `yaml:"huge,omitempty"`
-	TooHigh     *float32 `yaml:"toohigh,omitempty"`
-}
-
-type YamlMeasures struct {
-	Measures []YamlCalibration `yaml:"measures"`
-}
-
 func main() {
-	files, err := filepath.Glob("./rivers/*.yaml")
+	content, problems, err := river.LoadDir(".")
 	die(err)
 
-	var sections []river.Section
-	calibrations := make(map[string][]river.Calibration)
-
-nextFile:
-	for _, fn := range files {
-		y, err := ioutil.ReadFile(fn)
-		die(err)
-
-		// parse a section from yaml
-		var s river.Section
-		err = yaml.Unmarshal(y, &s)
-		die(err)
-		s.Slug = strings.TrimSuffix(filepath.Base(fn), ".yaml")
-		sections = append(sections, s)
-
-		// parse a calibration from yaml
-		var m YamlMeasures
-		err = yaml.Unmarshal(y, &m)
-		die(err)
-		if len(m.Measures) == 0 {
-			continue nextFile
-		}
-		var all []river.Calibration
-		for _, yc := range m.Measures {
-			all = append(all, yamlCalibrationToRiverCalibration(yc))
-		}
-		calibrations[s.UUID] = all
-	}
+	// report every problem before failing on any that are fatal
+	reportProblems(problems)
 
 	f, err := os.Create("rivers.go")
 	die(err)
 	defer f.Close()
 
-	packageTemplate.Execute(f, struct {
-		Timestamp    time.Time
-		Sections     []river.Section
-		Calibrations map[string][]river.Calibration
-	}{
-		Timestamp:    time.Now(),
-		Sections:     sections,
-		Calibrations: calibrations,
-	})
+	err = packageTemplate.Execute(f, content)
+	die(err)
+}
+
+func reportProblems(problems map[string][]river.Problem) {
+	var files []string
+	for fn := range problems {
+		files = append(files, fn)
+	}
+	sort.Strings(files)
+
+	nFatal := 0
+	for _, fn := range files {
+		for _, p := range problems[fn] {
+			log.Println(fn + ": " + p.String())
+			if p.IsFatal {
+				nFatal++
+			}
+		}
+	}
+	if nFatal > 0 {
+		log.Fatal(strconv.Itoa(nFatal) + " content errors")
+	}
 }
 
 func die(err error) {
@@ -89,9 +59,8 @@ func die(err error) {
 }
 
 var packageTemplate = template.Must(template.New("").Parse(`// Code generated by go generate; DO NOT EDIT.
-// This file was generated by robots at
-// {{ .Timestamp }}
-// using data from /rivers content directory
+// This file was generated by robots using data
+// from the /rivers & /releases content directories
 package rainchasers
 
 import "github.com/robtuley/rainchasers/internal/river"
@@ -103,6 +72,13 @@ var Calibrations = map[string][]river.Calibration{
 {{- end }}
 }
 
+// Releases define those sections with a dam release calendar in uuid keyed map
+var Releases = map[string]river.Calendar{
+{{- range $key, $value := .Releases }}
+	{{ printf "%#v" $key }}: {{ printf "%#v" $value }},
+{{- end }}
+}
+
 // Sections are the river sectionn definitions
 var Sections = []river.Section{
 {{- range .Sections }}
@@ -111,29 +87,3 @@ var Sections = []river.Section{
 }
 `))
 
-func yamlCalibrationToRiverCalibration(yc YamlCalibration) river.Calibration {
-	c := river.Calibration{
-		URL:         yc.URL,
-		Description: yc.Description,
-		Minimum:     make(map[string]float32),
-	}
-	if yc.Scrape != nil {
-		c.Minimum[river.Scrape.String()] = *yc.Scrape
-	}
-	if yc.Low != nil {
-		c.Minimum[river.Low.String()] = *yc.Low
-	}
-	if yc.Medium != nil {
-		c.Minimum[river.Medium.String()] = *yc.Medium
-	}
-	if yc.High != nil {
-		c.Minimum[river.High.String()] = *yc.High
-	}
-	if yc.Huge != nil {
-		c.Minimum[river.Huge.String()] = *yc.Huge
-	}
-	if yc.TooHigh != nil {
-		c.Minimum[river.TooHigh.String()] = *yc.TooHigh
-	}
-	return c
-}
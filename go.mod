@@ -12,7 +12,8 @@ require (
 	github.com/thingful/osgridconverter v0.0.0-20170127114542-06789ac2e515
 	go.uber.org/goleak v0.10.0
 	golang.org/x/tools v0.0.0-20200918232735-d647fc253266 // indirect
-	google.golang.org/api v0.32.0 // indirect
+	google.golang.org/api v0.32.0
 	google.golang.org/genproto v0.0.0-20200921151605-7abf4a1a14d5 // indirect
 	google.golang.org/grpc v1.32.0
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -109,11 +109,16 @@ func (d *Supervisor) CloseWait() {
 	d.Wait()
 }
 
+// cancelOnClose cancels the context when the daemon closes, without holding
+// on to a goroutine once the context is otherwise cancelled
 func (d *Supervisor) cancelOnClose(ctx context.Context) context.Context {
 	ctx, cancel := context.WithCancel(ctx)
 	d.wg.Add(1)
 	go func() {
-		<-d.doneC
+		select {
+		case <-d.doneC:
+		case <-ctx.Done():
+		}
 		cancel()
 		d.wg.Done()
 	}()
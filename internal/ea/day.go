@@ -30,7 +30,24 @@ func Day(ctx context.Context, day time.Time) (map[string][]gauge.Reading, report
 	}
 	defer resp.Body.Close()
 
-	csv := csv.NewReader(resp.Body)
+	readings, err = ParseDay(resp.Body)
+	if err != nil {
+		return readings, span.End(err)
+	}
+
+	n := 0
+	for _, r := range readings {
+		n += len(r)
+	}
+	span = span.Field("stations_count", len(readings))
+	span = span.Field("readings_count", n)
+	return readings, span.End()
+}
+
+// ParseDay reads all the measurements from a daily archive CSV
+func ParseDay(body io.Reader) (map[string][]gauge.Reading, error) {
+	readings := make(map[string][]gauge.Reading)
+	csv := csv.NewReader(body)
 	isFirst := true
 
 ReadCSV:
@@ -48,7 +65,7 @@ ReadCSV:
 			}
 		}
 		if err != nil {
-			return readings, span.End(err)
+			return readings, err
 		}
 		if isFirst {
 			isFirst = false
@@ -57,19 +74,13 @@ ReadCSV:
 
 		url, s, err := csvRecordToReading(r)
 		if err != nil {
-			return readings, span.End(err)
+			return readings, err
 		}
 
 		readings[url] = append(readings[url], s)
 	}
 
-	n := 0
-	for _, r := range readings {
-		n += len(r)
-	}
-	span = span.Field("stations_count", len(readings))
-	span = span.Field("readings_count", n)
-	return readings, span.End()
+	return readings, nil
 }
 
 // 2016-01-30T00:00:00Z,http://environment.data.gov.uk/flood-monitoring/id/measures/0569TH-level-stage-i-15_min-mASD,3.430
@@ -2,6 +2,7 @@ package ea
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 )
@@ -27,3 +28,26 @@ func TestDayCSVParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDay(t *testing.T) {
+	const archive = `dateTime,measure,value
+2016-01-30T00:00:00Z,http://environment.data.gov.uk/flood-monitoring/id/measures/0569TH-level-stage-i-15_min-mASD,3.430
+2016-01-30T00:15:00Z,http://environment.data.gov.uk/flood-monitoring/id/measures/0569TH-level-stage-i-15_min-mASD,3.440
+2016-01-30T00:15:00Z,http://environment.data.gov.uk/flood-monitoring/id/measures/0569TH-level-stage-i-15_min-mASD,1.23|4.56
+2016-01-30T00:00:00Z,http://environment.data.gov.uk/flood-monitoring/id/measures/1029TH-level-downstage-i-15_min-mASD,0.120
+`
+	readings, err := ParseDay(strings.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 2 {
+		t.Fatal("Unexpected gauges count", len(readings))
+	}
+	r := readings["http://environment.data.gov.uk/flood-monitoring/id/measures/0569TH-level-stage-i-15_min-mASD"]
+	if len(r) != 2 {
+		t.Fatal("Unexpected readings count", len(r))
+	}
+	if r[1].Value != 3.44 || r[1].EventTime.Minute() != 15 {
+		t.Error("Unexpected reading", r[1])
+	}
+}
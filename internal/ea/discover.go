@@ -3,6 +3,7 @@ package ea
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"time"
 
 	"github.com/robtuley/rainchasers/internal/daemon"
@@ -27,6 +28,26 @@ type stationJson struct {
 	LatRawJson       json.RawMessage `json:"lat"`
 	LgRawJson        json.RawMessage `json:"long"`
 	Measures         []measureJson   `json:"measures"`
+
+	// optional metadata, only provided in the full view
+	CatchmentRawJson      json.RawMessage `json:"catchmentName"`
+	WiskiIDRawJson        json.RawMessage `json:"wiskiID"`
+	DatumRawJson          json.RawMessage `json:"datumOffset"`
+	StageScaleRawJson     json.RawMessage `json:"stageScale"`
+	DownstageScaleRawJson json.RawMessage `json:"downstageScale"`
+}
+
+type scaleJson struct {
+	DatumRawJson  json.RawMessage `json:"datum"`
+	LowRawJson    json.RawMessage `json:"typicalRangeLow"`
+	HighRawJson   json.RawMessage `json:"typicalRangeHigh"`
+	MinOnRecord   recordJson      `json:"minOnRecord"`
+	MaxOnRecord   recordJson      `json:"maxOnRecord"`
+	HighestRecent recordJson      `json:"highestRecent"`
+}
+
+type recordJson struct {
+	ValueRawJson json.RawMessage `json:"value"`
 }
 
 type measureJson struct {
@@ -41,23 +62,35 @@ func Discover(ctx context.Context) (map[string]gauge.Station, report.Span) {
 	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	url := "http://environment.data.gov.uk/flood-monitoring/id/stations"
+	url := "http://environment.data.gov.uk/flood-monitoring/id/stations?_view=full"
 	span := report.StartSpan("ea.discover").Field("url", url)
-	stations := make(map[string]gauge.Station)
 
 	resp, err := daemon.JSON(ctx, url)
 	if err != nil {
-		return stations, span.End(err)
+		return make(map[string]gauge.Station), span.End(err)
 	}
 	defer resp.Body.Close()
 
-	list := stationListJson{}
-	decoder := json.NewDecoder(resp.Body)
-	err = decoder.Decode(&list)
+	stations, err := ParseStations(resp.Body)
 	if err != nil {
 		return stations, span.End(err)
 	}
 
+	span = span.Field("stations_count", len(stations))
+	return stations, span.End()
+}
+
+// ParseStations reads the station list, keyed by measure URL
+func ParseStations(body io.Reader) (map[string]gauge.Station, error) {
+	stations := make(map[string]gauge.Station)
+
+	list := stationListJson{}
+	decoder := json.NewDecoder(body)
+	err := decoder.Decode(&list)
+	if err != nil {
+		return stations, err
+	}
+
 	for _, s := range list.Stations {
 		// a known inconsistency is that the API can provide Lat, Lg or label as an array
 		// so we use a defensive mechanism to parse these fields and let them be missing completely
@@ -66,6 +99,8 @@ func Discover(ctx context.Context) (map[string]gauge.Station, report.Span) {
 		s.RLOIid, _ = daemon.ParseString(s.RLOIidRawJson)
 		s.Name, _ = daemon.ParseString(s.NameRawJson)
 		s.RiverName, _ = daemon.ParseString(s.RiverNameRawJson)
+		catchment, _ := daemon.ParseString(s.CatchmentRawJson)
+		wiskiID, _ := daemon.ParseString(s.WiskiIDRawJson)
 
 		for _, m := range s.Measures {
 
@@ -87,20 +122,64 @@ func Discover(ctx context.Context) (map[string]gauge.Station, report.Span) {
 			// s.DateTime and s.Value left as defaults
 
 			station := gauge.Station{
-				DataURL:   m.Url,
-				AliasURL:  aliasURL,
-				HumanURL:  s.Url,
-				Name:      s.Name,
-				RiverName: s.RiverName,
-				Lat:       s.Lat,
-				Lg:        s.Lg,
-				Type:      m.Type,
-				Unit:      m.Unit,
+				DataURL:       m.Url,
+				AliasURL:      aliasURL,
+				HumanURL:      s.Url,
+				Name:          s.Name,
+				RiverName:     s.RiverName,
+				Lat:           s.Lat,
+				Lg:            s.Lg,
+				Type:          m.Type,
+				Unit:          m.Unit,
+				CatchmentName: catchment,
+				WiskiID:       wiskiID,
+			}
+
+			// the stage scales describe the level measures upstream
+			// and downstream of a structure, and are either a link
+			// or missing outside the full view
+			if m.Type == "level" {
+				scale := s.StageScaleRawJson
+				if m.Name == "Downstream Stage" {
+					scale = s.DownstageScaleRawJson
+				}
+				station.Datum = optionalFloat(s.DatumRawJson)
+				addScale(&station, scale)
 			}
+
 			stations[m.Url] = station
 		}
 	}
 
-	span = span.Field("stations_count", len(stations))
-	return stations, span.End()
+	return stations, nil
+}
+
+// addScale adds the typical range and records from a stage scale
+func addScale(station *gauge.Station, raw json.RawMessage) {
+	scale := scaleJson{}
+	if err := json.Unmarshal(raw, &scale); err != nil {
+		return
+	}
+
+	if datum := optionalFloat(scale.DatumRawJson); datum != nil {
+		station.Datum = datum
+	}
+	station.Low = optionalFloat(scale.LowRawJson)
+	station.High = optionalFloat(scale.HighRawJson)
+	station.Lowest = optionalFloat(scale.MinOnRecord.ValueRawJson)
+	station.Highest = optionalFloat(scale.MaxOnRecord.ValueRawJson)
+	if station.Highest == nil {
+		station.Highest = optionalFloat(scale.HighestRecent.ValueRawJson)
+	}
+}
+
+func optionalFloat(raw json.RawMessage) *float32 {
+	if len(raw) == 0 {
+		return nil
+	}
+	f, err := daemon.ParseFloat(raw)
+	if err != nil {
+		return nil
+	}
+	return &f
 }
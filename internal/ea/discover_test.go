@@ -3,6 +3,7 @@ package ea
 import (
 	"context"
 	"math"
+	"strings"
 	"testing"
 )
 
@@ -69,3 +70,117 @@ func TestDiscoveringStations(t *testing.T) {
 		t.Error("Too many missing lg", nMissingLg, len(stations))
 	}
 }
+
+const fullStationsResponse = `{
+  "items": [
+    {
+      "@id": "http://environment.data.gov.uk/flood-monitoring/id/stations/1029TH",
+      "RLOIid": "1234",
+      "catchmentName": "Cotswolds",
+      "datumOffset": 108.08,
+      "label": "Bourton Dickler",
+      "lat": 51.874767,
+      "long": -1.740083,
+      "riverName": "Dikler",
+      "wiskiID": "1029TH",
+      "measures": [
+        {
+          "@id": "http://environment.data.gov.uk/flood-monitoring/id/measures/1029TH-level-stage-i-15_min-mASD",
+          "parameter": "level",
+          "qualifier": "Stage",
+          "unitName": "mASD"
+        },
+        {
+          "@id": "http://environment.data.gov.uk/flood-monitoring/id/measures/1029TH-level-downstage-i-15_min-mASD",
+          "parameter": "level",
+          "qualifier": "Downstream Stage",
+          "unitName": "mASD"
+        },
+        {
+          "@id": "http://environment.data.gov.uk/flood-monitoring/id/measures/1029TH-flow--i-15_min-m3_s",
+          "parameter": "flow",
+          "qualifier": "",
+          "unitName": "m3/s"
+        }
+      ],
+      "stageScale": {
+        "@id": "http://environment.data.gov.uk/flood-monitoring/id/stations/1029TH/stageScale",
+        "datum": 108.08,
+        "highestRecent": {"dateTime": "2020-02-16T06:30:00Z", "value": 0.93},
+        "maxOnRecord": {"dateTime": "2007-07-20T18:00:00Z", "value": 1.39},
+        "minOnRecord": {"dateTime": "2018-07-01T12:00:00Z", "value": 0.03},
+        "scaleMax": 2,
+        "typicalRangeHigh": 0.6,
+        "typicalRangeLow": 0.12
+      },
+      "downstageScale": {
+        "@id": "http://environment.data.gov.uk/flood-monitoring/id/stations/1029TH/downstageScale",
+        "highestRecent": {"dateTime": "2020-02-16T06:30:00Z", "value": 0.51},
+        "typicalRangeHigh": 0.4,
+        "typicalRangeLow": 0.05
+      }
+    },
+    {
+      "@id": "http://environment.data.gov.uk/flood-monitoring/id/stations/E2043",
+      "label": ["Surrey Street", "Surrey St"],
+      "stageScale": "http://environment.data.gov.uk/flood-monitoring/id/stations/E2043/stageScale",
+      "measures": [
+        {
+          "@id": "http://environment.data.gov.uk/flood-monitoring/id/measures/E2043-level-stage-i-15_min-mASD",
+          "parameter": "level",
+          "qualifier": "Stage",
+          "unitName": "mASD"
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParsingStationMetadata(t *testing.T) {
+	stations, err := ParseStations(strings.NewReader(fullStationsResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != 4 {
+		t.Fatal("Expected 4 measures", len(stations))
+	}
+
+	equal := func(name string, got *float32, want float32) {
+		if got == nil {
+			t.Error(name, "missing")
+		} else if *got != want {
+			t.Error(name, "mis-match", *got, want)
+		}
+	}
+
+	stage := stations["http://environment.data.gov.uk/flood-monitoring/id/measures/1029TH-level-stage-i-15_min-mASD"]
+	if stage.AliasURL != "rloi://1234" || stage.CatchmentName != "Cotswolds" || stage.WiskiID != "1029TH" {
+		t.Error("Station metadata not parsed", stage)
+	}
+	equal("datum", stage.Datum, 108.08)
+	equal("low", stage.Low, 0.12)
+	equal("high", stage.High, 0.6)
+	equal("lowest", stage.Lowest, 0.03)
+	equal("highest", stage.Highest, 1.39)
+
+	downstage := stations["http://environment.data.gov.uk/flood-monitoring/id/measures/1029TH-level-downstage-i-15_min-mASD"]
+	equal("downstage low", downstage.Low, 0.05)
+	equal("downstage high", downstage.High, 0.4)
+	equal("downstage highest recent", downstage.Highest, 0.51)
+	if downstage.Lowest != nil {
+		t.Error("Downstage lowest should be missing", *downstage.Lowest)
+	}
+
+	flow := stations["http://environment.data.gov.uk/flood-monitoring/id/measures/1029TH-flow--i-15_min-m3_s"]
+	if flow.Low != nil || flow.High != nil || flow.Datum != nil {
+		t.Error("Stage scale applied to a flow measure", flow)
+	}
+	if flow.WiskiID != "1029TH" {
+		t.Error("WISKI id not shared by all measures", flow.WiskiID)
+	}
+
+	linked := stations["http://environment.data.gov.uk/flood-monitoring/id/measures/E2043-level-stage-i-15_min-mASD"]
+	if linked.Name != "Surrey Street" || linked.Low != nil || linked.Datum != nil {
+		t.Error("Linked stage scale not ignored", linked)
+	}
+}
@@ -0,0 +1,128 @@
+package eahydrology
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/report"
+)
+
+const stationsURL = "https://environment.data.gov.uk/hydrology/id/stations?_limit=10000"
+
+// floodMonitoringStationURL is the prefix of the station URLs ea.Discover
+// uses as a human URL, which share the station reference
+const floodMonitoringStationURL = "http://environment.data.gov.uk/flood-monitoring/id/stations/"
+
+// instantaneousPeriod is the 15 minute period of the measures that match
+// the readings published by the flood monitoring API
+const instantaneousPeriod = 900
+
+type stationListJSON struct {
+	Stations []stationJSON `json:"items"`
+}
+
+type stationJSON struct {
+	URL               string          `json:"@id"`
+	NameRawJSON       json.RawMessage `json:"label"`
+	RiverNameRawJSON  json.RawMessage `json:"riverName"`
+	RLOIidRawJSON     json.RawMessage `json:"RLOIid"`
+	StationRefRawJSON json.RawMessage `json:"stationReference"`
+	WiskiIDRawJSON    json.RawMessage `json:"wiskiID"`
+	LatRawJSON        json.RawMessage `json:"lat"`
+	LgRawJSON         json.RawMessage `json:"long"`
+	Measures          []measureJSON   `json:"measures"`
+}
+
+type measureJSON struct {
+	URL    string `json:"@id"`
+	Type   string `json:"parameter"`
+	Unit   string `json:"unitName"`
+	Period int    `json:"period"`
+}
+
+// Discover finds all the Hydrology API stations with 15 minute readings
+func Discover(ctx context.Context) (map[string]gauge.Station, report.Span) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	span := report.StartSpan("eahydrology.discover").Field("url", stationsURL)
+
+	resp, err := daemon.JSON(ctx, stationsURL)
+	if err != nil {
+		return make(map[string]gauge.Station), span.End(err)
+	}
+	defer resp.Body.Close()
+
+	stations, err := ParseStations(resp.Body)
+	if err != nil {
+		return stations, span.End(err)
+	}
+
+	span = span.Field("stations_count", len(stations))
+	return stations, span.End()
+}
+
+// ParseStations reads the Hydrology API station list, keyed by measure URL
+//
+// Stations are given the same alias and human URLs as ea.Discover so the
+// readings are routed to the same calibrations.
+func ParseStations(body io.Reader) (map[string]gauge.Station, error) {
+	stations := make(map[string]gauge.Station)
+
+	list := stationListJSON{}
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return stations, err
+	}
+
+	for _, s := range list.Stations {
+		// as with the flood monitoring API, fields can be provided as an
+		// array or be missing completely so parse them defensively
+		lat, _ := daemon.ParseFloat(s.LatRawJSON)
+		lg, _ := daemon.ParseFloat(s.LgRawJSON)
+		name, _ := daemon.ParseString(s.NameRawJSON)
+		riverName, _ := daemon.ParseString(s.RiverNameRawJSON)
+		rloiID, _ := daemon.ParseString(s.RLOIidRawJSON)
+		stationRef, _ := daemon.ParseString(s.StationRefRawJSON)
+		wiskiID, _ := daemon.ParseString(s.WiskiIDRawJSON)
+
+		humanURL := s.URL
+		if stationRef != "" {
+			humanURL = floodMonitoringStationURL + stationRef
+		}
+
+		for _, m := range s.Measures {
+			if m.Period != instantaneousPeriod {
+				continue
+			}
+			switch m.Type {
+			case "flow", "level", "rainfall":
+			default:
+				continue
+			}
+
+			aliasURL := m.URL
+			if rloiID != "" {
+				aliasURL = "rloi://" + rloiID
+			}
+
+			stations[m.URL] = gauge.Station{
+				DataURL:   m.URL,
+				AliasURL:  aliasURL,
+				HumanURL:  humanURL,
+				Name:      name,
+				RiverName: riverName,
+				Lat:       lat,
+				Lg:        lg,
+				Type:      m.Type,
+				Unit:      m.Unit,
+				WiskiID:   wiskiID,
+			}
+		}
+	}
+
+	return stations, nil
+}
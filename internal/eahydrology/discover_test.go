@@ -0,0 +1,80 @@
+package eahydrology
+
+import (
+	"strings"
+	"testing"
+)
+
+const stationsResponse = `{
+  "meta": {"publisher": "Environment Agency"},
+  "items": [
+    {
+      "@id": "http://environment.data.gov.uk/hydrology/id/stations/052d0819-2a32-47df-9b99-c243c9c8235b",
+      "label": "Bourton Dickler",
+      "riverName": "Dikler",
+      "RLOIid": "1234",
+      "stationReference": "1029TH",
+      "wiskiID": "1029TH",
+      "lat": 51.874767,
+      "long": -1.740083,
+      "measures": [
+        {
+          "@id": "http://environment.data.gov.uk/hydrology/id/measures/052d0819-2a32-47df-9b99-c243c9c8235b-level-i-900-m-qualified",
+          "parameter": "level",
+          "unitName": "m",
+          "period": 900
+        },
+        {
+          "@id": "http://environment.data.gov.uk/hydrology/id/measures/052d0819-2a32-47df-9b99-c243c9c8235b-level-max-86400-m-qualified",
+          "parameter": "level",
+          "unitName": "m",
+          "period": 86400
+        }
+      ]
+    },
+    {
+      "@id": "http://environment.data.gov.uk/hydrology/id/stations/3c2a2b5e-1a2b-4c3d-9e8f-0a1b2c3d4e5f",
+      "label": ["Upper Gauge", "Upper Gauge Alt"],
+      "measures": [
+        {
+          "@id": "http://environment.data.gov.uk/hydrology/id/measures/3c2a2b5e-1a2b-4c3d-9e8f-0a1b2c3d4e5f-rainfall-t-900-mm-qualified",
+          "parameter": "rainfall",
+          "unitName": "mm",
+          "period": 900
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseStations(t *testing.T) {
+	stations, err := ParseStations(strings.NewReader(stationsResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != 2 {
+		t.Fatal("expected only 15 minute measures, got", len(stations))
+	}
+
+	level := stations["http://environment.data.gov.uk/hydrology/id/measures/052d0819-2a32-47df-9b99-c243c9c8235b-level-i-900-m-qualified"]
+	if level.AliasURL != "rloi://1234" {
+		t.Error("RLOI alias not mapped", level.AliasURL)
+	}
+	if level.HumanURL != "http://environment.data.gov.uk/flood-monitoring/id/stations/1029TH" {
+		t.Error("flood monitoring station not mapped", level.HumanURL)
+	}
+	if level.Name != "Bourton Dickler" || level.RiverName != "Dikler" || level.Type != "level" || level.Unit != "m" {
+		t.Error("station not parsed", level)
+	}
+	if level.Lat < 51.8 || level.Lg > -1.7 {
+		t.Error("location not parsed", level.Lat, level.Lg)
+	}
+	if level.WiskiID != "1029TH" {
+		t.Error("WISKI id not parsed", level.WiskiID)
+	}
+
+	rain := stations["http://environment.data.gov.uk/hydrology/id/measures/3c2a2b5e-1a2b-4c3d-9e8f-0a1b2c3d4e5f-rainfall-t-900-mm-qualified"]
+	if rain.AliasURL != rain.DataURL || rain.Name != "Upper Gauge" || rain.Type != "rainfall" {
+		t.Error("station without RLOI id not parsed", rain)
+	}
+}
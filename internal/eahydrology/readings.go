@@ -0,0 +1,99 @@
+package eahydrology
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/daemon"
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/report"
+)
+
+// checkedQuality are the quality codes of readings that have passed the EA
+// quality checks, any other reading such as Unchecked or Suspect is skipped
+var checkedQuality = map[string]bool{
+	"Good":      true,
+	"Estimated": true,
+}
+
+// maxReadings is the page size requested, which comfortably covers a
+// month of 15 minute readings
+const maxReadings = 5000
+
+type readingListJSON struct {
+	Items []struct {
+		DateTime     string          `json:"dateTime"`
+		ValueRawJSON json.RawMessage `json:"value"`
+		Quality      string          `json:"quality"`
+	} `json:"items"`
+}
+
+// Readings fetches the quality checked readings for a measure between two
+// dates, including readings on the to date
+func Readings(ctx context.Context, measureURL string, from time.Time, to time.Time) ([]gauge.Reading, report.Span) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	q := url.Values{}
+	q.Set("mineq-date", from.Format("2006-01-02"))
+	q.Set("maxeq-date", to.Format("2006-01-02"))
+	q.Set("_limit", strconv.Itoa(maxReadings))
+	u := measureURL + "/readings?" + q.Encode()
+	span := report.StartSpan("eahydrology.readings").Field("url", u)
+
+	resp, err := daemon.JSON(ctx, u)
+	if err != nil {
+		return nil, span.End(err)
+	}
+	defer resp.Body.Close()
+
+	readings, err := ParseReadings(resp.Body)
+	if err != nil {
+		return readings, span.End(err)
+	}
+
+	span = span.Field("readings_count", len(readings))
+	return readings, span.End()
+}
+
+// ParseReadings reads a Hydrology API reading list, keeping only readings
+// that have passed the quality checks, newest first
+func ParseReadings(body io.Reader) ([]gauge.Reading, error) {
+	list := readingListJSON{}
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	var readings []gauge.Reading
+	for _, item := range list.Items {
+		if !checkedQuality[item.Quality] {
+			continue
+		}
+		value, err := daemon.ParseFloat(item.ValueRawJSON)
+		if err != nil {
+			continue
+		}
+		// date times are in UTC, but without a zone
+		t, err := time.Parse("2006-01-02T15:04:05", item.DateTime)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, item.DateTime)
+			if err != nil {
+				continue
+			}
+		}
+		readings = append(readings, gauge.Reading{
+			EventTime: t,
+			Value:     value,
+		})
+	}
+
+	sort.Slice(readings, func(i, j int) bool {
+		return readings[i].EventTime.After(readings[j].EventTime)
+	})
+	return readings, nil
+}
@@ -0,0 +1,38 @@
+package eahydrology
+
+import (
+	"strings"
+	"testing"
+)
+
+const readingsResponse = `{
+  "meta": {"publisher": "Environment Agency"},
+  "items": [
+    {"dateTime": "2020-02-15T00:00:00", "date": "2020-02-15", "value": 0.3, "quality": "Good"},
+    {"dateTime": "2020-02-15T00:15:00", "date": "2020-02-15", "value": 0.31, "quality": "Estimated"},
+    {"dateTime": "2020-02-15T00:30:00", "date": "2020-02-15", "quality": "Missing"},
+    {"dateTime": "2020-02-15T00:45:00", "date": "2020-02-15", "value": 9.99, "quality": "Suspect"},
+    {"dateTime": "2020-02-15T01:00:00", "date": "2020-02-15", "value": 0.35, "quality": "Unchecked"}
+  ]
+}`
+
+func TestParseReadings(t *testing.T) {
+	readings, err := ParseReadings(strings.NewReader(readingsResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 2 {
+		t.Fatal("expected missing, suspect and unchecked readings skipped", readings)
+	}
+	for _, r := range readings {
+		if r.Value == 0.35 {
+			t.Error("expected unchecked reading skipped", r)
+		}
+	}
+	if readings[0].Value != 0.31 || readings[0].EventTime.Minute() != 15 {
+		t.Error("expected newest reading first", readings[0])
+	}
+	if readings[1].EventTime.Location().String() != "UTC" {
+		t.Error("expected UTC time", readings[1].EventTime)
+	}
+}
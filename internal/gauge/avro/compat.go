@@ -0,0 +1,103 @@
+package avro
+
+import (
+	"io"
+)
+
+// SnapshotOptionalFields are the optional fields added to the end of the
+// snapshot schema after it was first deployed, in schema order
+var SnapshotOptionalFields = []string{
+	"datum",
+	"catchment_name",
+	"catchment_area",
+	"lowest",
+	"low",
+	"high",
+	"highest",
+	"mean",
+	"info_url",
+	"wiski_id",
+}
+
+// DeserializeSnapshotWithOptionalFields reads a snapshot written with an
+// earlier version of the schema that had only the first n optional fields,
+// leaving the later optional fields null
+//
+// This is needed where records follow one another, such as in container
+// files, as reading a missing field would consume the next record.
+func DeserializeSnapshotWithOptionalFields(r io.Reader, n int) (*Snapshot, error) {
+	var str = &Snapshot{}
+	var err error
+	str.Data_url, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Alias_url, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Human_url, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Name, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.River_name, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Lat, err = readFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Lg, err = readFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Unit, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Type, err = readTypeValues(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Readings, err = readArrayMeasure(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Correlation_id, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Causation_id, err = readString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Processed_time, err = readLong(r)
+	if err != nil {
+		return nil, err
+	}
+
+	optional := []func() error{
+		func() (err error) { str.Datum, err = readUnionNullFloat(r); return },
+		func() (err error) { str.Catchment_name, err = readUnionNullString(r); return },
+		func() (err error) { str.Catchment_area, err = readUnionNullFloat(r); return },
+		func() (err error) { str.Lowest, err = readUnionNullFloat(r); return },
+		func() (err error) { str.Low, err = readUnionNullFloat(r); return },
+		func() (err error) { str.High, err = readUnionNullFloat(r); return },
+		func() (err error) { str.Highest, err = readUnionNullFloat(r); return },
+		func() (err error) { str.Mean, err = readUnionNullFloat(r); return },
+		func() (err error) { str.Info_url, err = readUnionNullString(r); return },
+		func() (err error) { str.Wiski_id, err = readUnionNullString(r); return },
+	}
+	for i := 0; i < n && i < len(optional); i++ {
+		if err := optional[i](); err != nil {
+			return nil, err
+		}
+	}
+
+	return str, nil
+}
@@ -241,6 +241,46 @@ func readSnapshot(r io.Reader) (*Snapshot, error) {
 	if err != nil {
 		return nil, err
 	}
+	str.Datum, err = readUnionNullFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Catchment_name, err = readUnionNullString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Catchment_area, err = readUnionNullFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Lowest, err = readUnionNullFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Low, err = readUnionNullFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.High, err = readUnionNullFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Highest, err = readUnionNullFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Mean, err = readUnionNullFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Info_url, err = readUnionNullString(r)
+	if err != nil {
+		return nil, err
+	}
+	str.Wiski_id, err = readUnionNullString(r)
+	if err != nil {
+		return nil, err
+	}
 
 	return str, nil
 }
@@ -297,6 +337,52 @@ func readUnionMeasureSnapshot(r io.Reader) (UnionMeasureSnapshot, error) {
 	return unionStr, nil
 }
 
+func readUnionNullFloat(r io.Reader) (UnionNullFloat, error) {
+	field, err := readLong(r)
+	var unionStr UnionNullFloat
+	if err != nil {
+		return unionStr, err
+	}
+	unionStr.UnionType = UnionNullFloatTypeEnum(field)
+	switch unionStr.UnionType {
+	case UnionNullFloatTypeEnumNull:
+		// no value to read
+	case UnionNullFloatTypeEnumFloat:
+		val, err := readFloat(r)
+		if err != nil {
+			return unionStr, err
+		}
+		unionStr.Float = val
+
+	default:
+		return unionStr, fmt.Errorf("Invalid value for UnionNullFloat")
+	}
+	return unionStr, nil
+}
+
+func readUnionNullString(r io.Reader) (UnionNullString, error) {
+	field, err := readLong(r)
+	var unionStr UnionNullString
+	if err != nil {
+		return unionStr, err
+	}
+	unionStr.UnionType = UnionNullStringTypeEnum(field)
+	switch unionStr.UnionType {
+	case UnionNullStringTypeEnumNull:
+		// no value to read
+	case UnionNullStringTypeEnumString:
+		val, err := readString(r)
+		if err != nil {
+			return unionStr, err
+		}
+		unionStr.String = val
+
+	default:
+		return unionStr, fmt.Errorf("Invalid value for UnionNullString")
+	}
+	return unionStr, nil
+}
+
 func writeArrayMeasure(r []*Measure, w io.Writer) error {
 	err := writeLong(int64(len(r)), w)
 	if err != nil || len(r) == 0 {
@@ -398,6 +484,46 @@ func writeSnapshot(r *Snapshot, w io.Writer) error {
 	if err != nil {
 		return err
 	}
+	err = writeUnionNullFloat(r.Datum, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullString(r.Catchment_name, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullFloat(r.Catchment_area, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullFloat(r.Lowest, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullFloat(r.Low, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullFloat(r.High, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullFloat(r.Highest, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullFloat(r.Mean, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullString(r.Info_url, w)
+	if err != nil {
+		return err
+	}
+	err = writeUnionNullString(r.Wiski_id, w)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -433,3 +559,33 @@ func writeUnionMeasureSnapshot(r UnionMeasureSnapshot, w io.Writer) error {
 	}
 	return fmt.Errorf("Invalid value for UnionMeasureSnapshot")
 }
+
+func writeUnionNullFloat(r UnionNullFloat, w io.Writer) error {
+	err := writeLong(int64(r.UnionType), w)
+	if err != nil {
+		return err
+	}
+	switch r.UnionType {
+	case UnionNullFloatTypeEnumNull:
+		return nil
+	case UnionNullFloatTypeEnumFloat:
+		return writeFloat(r.Float, w)
+
+	}
+	return fmt.Errorf("Invalid value for UnionNullFloat")
+}
+
+func writeUnionNullString(r UnionNullString, w io.Writer) error {
+	err := writeLong(int64(r.UnionType), w)
+	if err != nil {
+		return err
+	}
+	switch r.UnionType {
+	case UnionNullStringTypeEnumNull:
+		return nil
+	case UnionNullStringTypeEnumString:
+		return writeString(r.String, w)
+
+	}
+	return fmt.Errorf("Invalid value for UnionNullString")
+}
@@ -24,6 +24,16 @@ type Snapshot struct {
 	Correlation_id string
 	Causation_id   string
 	Processed_time int64
+	Datum          UnionNullFloat
+	Catchment_name UnionNullString
+	Catchment_area UnionNullFloat
+	Lowest         UnionNullFloat
+	Low            UnionNullFloat
+	High           UnionNullFloat
+	Highest        UnionNullFloat
+	Mean           UnionNullFloat
+	Info_url       UnionNullString
+	Wiski_id       UnionNullString
 }
 
 func DeserializeSnapshot(r io.Reader) (*Snapshot, error) {
@@ -39,7 +49,7 @@ func NewSnapshot() *Snapshot {
 }
 
 func (r *Snapshot) Schema() string {
-	return "{\"doc:\":\"Gauge measurement record information and reading snapshot\",\"fields\":[{\"doc\":\"Data URL for the gauge measurement\",\"name\":\"data_url\",\"type\":\"string\"},{\"doc\":\"Alias URL as a reference to this station\",\"name\":\"alias_url\",\"type\":\"string\"},{\"doc\":\"Human linkable URL for the station\",\"name\":\"human_url\",\"type\":\"string\"},{\"doc\":\"Human-readable name of the measurement\",\"name\":\"name\",\"type\":\"string\"},{\"doc\":\"Name of the river measured\",\"name\":\"river_name\",\"type\":\"string\"},{\"doc\":\"Location latitude\",\"name\":\"lat\",\"type\":\"float\"},{\"doc\":\"Location longitude\",\"name\":\"lg\",\"type\":\"float\"},{\"doc\":\"Measurement unit\",\"name\":\"unit\",\"type\":\"string\"},{\"doc\":\"Measurement type\",\"name\":\"type\",\"type\":{\"name\":\"typeValues\",\"symbols\":[\"level\",\"flow\",\"temperature\",\"rainfall\"],\"type\":\"enum\"}},{\"name\":\"readings\",\"type\":{\"items\":{\"doc:\":\"Gauge measurement information\",\"fields\":[{\"doc\":\"Unix epoch time in seconds for measurement event time\",\"name\":\"event_time\",\"type\":\"long\"},{\"doc\":\"Measurement value\",\"name\":\"value\",\"type\":\"float\"}],\"name\":\"measure\",\"namespace\":\"com.rainchasers.gauge\",\"type\":\"record\"},\"type\":\"array\"}},{\"doc\":\"Correlation ID to generate this snapshot, can be used as a version identifier\",\"name\":\"correlation_id\",\"type\":\"string\"},{\"doc\":\"Causation ID to generate this snapshot\",\"name\":\"causation_id\",\"type\":\"string\"},{\"doc\":\"Unix epoch time in seconds for timestamp at which measurement was processed\",\"name\":\"processed_time\",\"type\":\"long\"}],\"name\":\"snapshot\",\"namespace\":\"com.rainchasers.gauge\",\"type\":\"record\"}"
+	return "{\"doc:\":\"Gauge measurement record information and reading snapshot\",\"fields\":[{\"doc\":\"Data URL for the gauge measurement\",\"name\":\"data_url\",\"type\":\"string\"},{\"doc\":\"Alias URL as a reference to this station\",\"name\":\"alias_url\",\"type\":\"string\"},{\"doc\":\"Human linkable URL for the station\",\"name\":\"human_url\",\"type\":\"string\"},{\"doc\":\"Human-readable name of the measurement\",\"name\":\"name\",\"type\":\"string\"},{\"doc\":\"Name of the river measured\",\"name\":\"river_name\",\"type\":\"string\"},{\"doc\":\"Location latitude\",\"name\":\"lat\",\"type\":\"float\"},{\"doc\":\"Location longitude\",\"name\":\"lg\",\"type\":\"float\"},{\"doc\":\"Measurement unit\",\"name\":\"unit\",\"type\":\"string\"},{\"doc\":\"Measurement type\",\"name\":\"type\",\"type\":{\"name\":\"typeValues\",\"symbols\":[\"level\",\"flow\",\"temperature\",\"rainfall\"],\"type\":\"enum\"}},{\"name\":\"readings\",\"type\":{\"items\":{\"doc:\":\"Gauge measurement information\",\"fields\":[{\"doc\":\"Unix epoch time in seconds for measurement event time\",\"name\":\"event_time\",\"type\":\"long\"},{\"doc\":\"Measurement value\",\"name\":\"value\",\"type\":\"float\"}],\"name\":\"measure\",\"namespace\":\"com.rainchasers.gauge\",\"type\":\"record\"},\"type\":\"array\"}},{\"doc\":\"Correlation ID to generate this snapshot, can be used as a version identifier\",\"name\":\"correlation_id\",\"type\":\"string\"},{\"doc\":\"Causation ID to generate this snapshot\",\"name\":\"causation_id\",\"type\":\"string\"},{\"doc\":\"Unix epoch time in seconds for timestamp at which measurement was processed\",\"name\":\"processed_time\",\"type\":\"long\"},{\"default\":null,\"doc\":\"Gauge datum in metres above ordnance datum\",\"name\":\"datum\",\"type\":[\"null\",\"float\"]},{\"default\":null,\"doc\":\"Name of the catchment upstream of the station\",\"name\":\"catchment_name\",\"type\":[\"null\",\"string\"]},{\"default\":null,\"doc\":\"Catchment area upstream of the station in square kilometres\",\"name\":\"catchment_area\",\"type\":[\"null\",\"float\"]},{\"default\":null,\"doc\":\"Lowest value on record\",\"name\":\"lowest\",\"type\":[\"null\",\"float\"]},{\"default\":null,\"doc\":\"Typical low value\",\"name\":\"low\",\"type\":[\"null\",\"float\"]},{\"default\":null,\"doc\":\"Typical high value\",\"name\":\"high\",\"type\":[\"null\",\"float\"]},{\"default\":null,\"doc\":\"Highest value on record\",\"name\":\"highest\",\"type\":[\"null\",\"float\"]},{\"default\":null,\"doc\":\"Mean value\",\"name\":\"mean\",\"type\":[\"null\",\"float\"]},{\"default\":null,\"doc\":\"URL of further hydrological information about the station\",\"name\":\"info_url\",\"type\":[\"null\",\"string\"]},{\"default\":null,\"doc\":\"WISKI identifier of the station, shared across EA APIs\",\"name\":\"wiski_id\",\"type\":[\"null\",\"string\"]}],\"name\":\"snapshot\",\"namespace\":\"com.rainchasers.gauge\",\"type\":\"record\"}"
 }
 
 func (r *Snapshot) Serialize(w io.Writer) error {
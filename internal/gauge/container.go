@@ -0,0 +1,302 @@
+package gauge
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/robtuley/rainchasers/internal/gauge/avro"
+)
+
+// containerMagic starts every AVRO Object Container File
+var containerMagic = []byte{'O', 'b', 'j', 1}
+
+// containerBlockSize is the encoded size at which a block is written out
+const containerBlockSize = 64 * 1024
+
+// ErrNotContainer is returned when reading a file that is not an AVRO
+// Object Container File
+var ErrNotContainer = errors.New("not an avro object container file")
+
+// ErrSchemaChanged is returned when appending to a container file written
+// with a different schema, as the file can only describe one schema
+var ErrSchemaChanged = errors.New("avro container written with a different schema")
+
+// ContainerWriter writes Snapshots as an AVRO Object Container File, so
+// the file can be read by standard AVRO tools
+//
+// Snapshots are buffered into blocks, and each block is written out with
+// a single write so a crash cannot leave a partial block.
+type ContainerWriter struct {
+	w     io.Writer
+	sync  [16]byte
+	block bytes.Buffer
+	count int64
+}
+
+// NewContainerWriter writes the file header and returns a writer
+// for the Snapshots that follow
+func NewContainerWriter(w io.Writer) (*ContainerWriter, error) {
+	cw := &ContainerWriter{w: w}
+	if _, err := rand.Read(cw.sync[:]); err != nil {
+		return nil, err
+	}
+
+	var bb bytes.Buffer
+	bb.Write(containerMagic)
+	writeContainerLong(&bb, 2)
+	writeContainerBytes(&bb, []byte("avro.schema"))
+	writeContainerBytes(&bb, []byte(avro.NewSnapshot().Schema()))
+	writeContainerBytes(&bb, []byte("avro.codec"))
+	writeContainerBytes(&bb, []byte("null"))
+	writeContainerLong(&bb, 0)
+	bb.Write(cw.sync[:])
+
+	if _, err := w.Write(bb.Bytes()); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// AppendContainerWriter reads the header of an existing container file and
+// returns a writer that appends Snapshots to the end of it, refusing with
+// ErrSchemaChanged if the file was written with a different schema
+func AppendContainerWriter(f io.ReadWriteSeeker) (*ContainerWriter, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	header, err := readContainerHeader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	if string(header.meta["avro.schema"]) != avro.NewSnapshot().Schema() {
+		return nil, ErrSchemaChanged
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return nil, err
+	}
+	return &ContainerWriter{w: f, sync: header.sync}, nil
+}
+
+// Write adds a Snapshot to the current block
+func (cw *ContainerWriter) Write(s *Snapshot) error {
+	if err := s.Encode(&cw.block); err != nil {
+		return err
+	}
+	cw.count++
+	if cw.block.Len() >= containerBlockSize {
+		return cw.Flush()
+	}
+	return nil
+}
+
+// Flush writes out the current block
+func (cw *ContainerWriter) Flush() error {
+	if cw.count == 0 {
+		return nil
+	}
+
+	var bb bytes.Buffer
+	writeContainerLong(&bb, cw.count)
+	writeContainerLong(&bb, int64(cw.block.Len()))
+	bb.Write(cw.block.Bytes())
+	bb.Write(cw.sync[:])
+	if _, err := cw.w.Write(bb.Bytes()); err != nil {
+		return err
+	}
+
+	cw.block.Reset()
+	cw.count = 0
+	return nil
+}
+
+// ContainerReader reads Snapshots from an AVRO Object Container File
+type ContainerReader struct {
+	r      *bufio.Reader
+	header containerHeader
+	block  *bytes.Reader
+	remain int64
+
+	// optional is the number of optional fields in the writer schema
+	optional int
+}
+
+// NewContainerReader reads the file header and returns a reader for the
+// Snapshots that follow
+func NewContainerReader(r io.Reader) (*ContainerReader, error) {
+	br := bufio.NewReader(r)
+	header, err := readContainerHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerReader{
+		r:        br,
+		header:   header,
+		optional: header.optionalFields(),
+	}, nil
+}
+
+// Next reads the next Snapshot, returning io.EOF once all are read
+func (cr *ContainerReader) Next() (*Snapshot, error) {
+	for cr.remain == 0 {
+		if err := cr.readBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	a, err := avro.DeserializeSnapshotWithOptionalFields(cr.block, cr.optional)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	s.fromAvro(a)
+	cr.remain--
+	return s, nil
+}
+
+func (cr *ContainerReader) readBlock() error {
+	count, err := readContainerLong(cr.r)
+	if err != nil {
+		// a clean end of file between blocks
+		return err
+	}
+	size, err := readContainerLong(cr.r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if count < 0 || size < 0 {
+		return errors.New("negative avro container block size")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(cr.r, data); err != nil {
+		return unexpectedEOF(err)
+	}
+	var sync [16]byte
+	if _, err := io.ReadFull(cr.r, sync[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	if sync != cr.header.sync {
+		return errors.New("avro container block sync marker mismatch")
+	}
+
+	cr.block = bytes.NewReader(data)
+	cr.remain = count
+	return nil
+}
+
+type containerHeader struct {
+	meta map[string][]byte
+	sync [16]byte
+}
+
+// optionalFields counts the optional fields in the writer schema, as files
+// written before a field was added to the schema do not contain it
+func (h containerHeader) optionalFields() int {
+	all := len(avro.SnapshotOptionalFields)
+	written, err := countSchemaFields(h.meta["avro.schema"])
+	if err != nil {
+		return all
+	}
+	current, err := countSchemaFields([]byte(avro.NewSnapshot().Schema()))
+	if err != nil {
+		return all
+	}
+	n := all - (current - written)
+	if n < 0 || n > all {
+		return all
+	}
+	return n
+}
+
+func countSchemaFields(schema []byte) (int, error) {
+	record := struct {
+		Fields []json.RawMessage `json:"fields"`
+	}{}
+	if err := json.Unmarshal(schema, &record); err != nil {
+		return 0, err
+	}
+	return len(record.Fields), nil
+}
+
+func readContainerHeader(r *bufio.Reader) (containerHeader, error) {
+	h := containerHeader{meta: make(map[string][]byte)}
+
+	magic := make([]byte, len(containerMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, containerMagic) {
+		return h, ErrNotContainer
+	}
+
+	for {
+		n, err := readContainerLong(r)
+		if err != nil {
+			return h, unexpectedEOF(err)
+		}
+		if n == 0 {
+			break
+		}
+		if n < 0 {
+			// a negative count is followed by the block size in bytes
+			n = -n
+			if _, err := readContainerLong(r); err != nil {
+				return h, unexpectedEOF(err)
+			}
+		}
+		for i := int64(0); i < n; i++ {
+			k, err := readContainerBytes(r)
+			if err != nil {
+				return h, unexpectedEOF(err)
+			}
+			v, err := readContainerBytes(r)
+			if err != nil {
+				return h, unexpectedEOF(err)
+			}
+			h.meta[string(k)] = v
+		}
+	}
+
+	if codec, ok := h.meta["avro.codec"]; ok && string(codec) != "null" {
+		return h, errors.New("unsupported avro container codec " + string(codec))
+	}
+	if _, err := io.ReadFull(r, h.sync[:]); err != nil {
+		return h, unexpectedEOF(err)
+	}
+	return h, nil
+}
+
+func writeContainerLong(bb *bytes.Buffer, n int64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	bb.Write(b[:binary.PutVarint(b, n)])
+}
+
+func writeContainerBytes(bb *bytes.Buffer, b []byte) {
+	writeContainerLong(bb, int64(len(b)))
+	bb.Write(b)
+}
+
+func readContainerLong(r *bufio.Reader) (int64, error) {
+	return binary.ReadVarint(r)
+}
+
+func readContainerBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := readContainerLong(r)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, errors.New("negative avro container byte length")
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,192 @@
+package gauge
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge/avro"
+)
+
+func TestContainerWriteAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.avro")
+	timestamp, _ := time.Parse(time.RFC3339, "2016-01-01T10:30:00Z")
+	snapshot := func(i int) *Snapshot {
+		return &Snapshot{
+			Station:       Station{DataURL: "rloi://" + strconv.Itoa(i), Type: "level"},
+			Readings:      []Reading{{EventTime: timestamp, Value: float32(i)}},
+			CorrelationID: strconv.Itoa(i),
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw, err := NewContainerWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// enough snapshots to span several blocks
+	n := 0
+	for ; n < 5000; n++ {
+		if err := cw.Write(snapshot(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	f, err = os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw, err = AppendContainerWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Write(snapshot(n)); err != nil {
+		t.Fatal(err)
+	}
+	n++
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr, err := NewContainerReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		s, err := cr.Next()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if s.CorrelationID != strconv.Itoa(i) || s.Readings[0].Value != float32(i) {
+			t.Fatal("snapshot mis-match", i, s)
+		}
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Error("expected EOF, got", err)
+	}
+}
+
+func TestContainerRejectsOtherFiles(t *testing.T) {
+	_, err := NewContainerReader(bytes.NewBufferString("not avro"))
+	if err != ErrNotContainer {
+		t.Error("expected not container error, got", err)
+	}
+}
+
+func TestContainerReadsEarlierSchema(t *testing.T) {
+	cr, err := NewContainerReader(bytes.NewReader(legacyContainer(t, 3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		s, err := cr.Next()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if s.CorrelationID != strconv.Itoa(i) {
+			t.Fatal("snapshot mis-match", i, s)
+		}
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Error("expected EOF, got", err)
+	}
+}
+
+func TestContainerRefusesAppendToEarlierSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.avro")
+	if err := ioutil.WriteFile(path, legacyContainer(t, 3), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AppendContainerWriter(f); err != ErrSchemaChanged {
+		t.Error("expected schema changed error, got", err)
+	}
+	f.Close()
+
+	// the earlier snapshots are left readable
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr, err := NewContainerReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		s, err := cr.Next()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if s.CorrelationID != strconv.Itoa(i) {
+			t.Fatal("snapshot mis-match", i, s)
+		}
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Error("expected EOF, got", err)
+	}
+}
+
+// legacyContainer is a container file of n snapshots written with the
+// schema from before the optional fields were added
+func legacyContainer(t *testing.T, n int) []byte {
+	// rewrite the current schema without the optional fields
+	schema := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(avro.NewSnapshot().Schema()), &schema); err != nil {
+		t.Fatal(err)
+	}
+	fields := schema["fields"].([]interface{})
+	schema["fields"] = fields[:len(fields)-len(avro.SnapshotOptionalFields)]
+	legacySchema, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block bytes.Buffer
+	for i := 0; i < n; i++ {
+		var bb bytes.Buffer
+		s := Snapshot{
+			Station:       Station{DataURL: "rloi://" + strconv.Itoa(i), Type: "level"},
+			CorrelationID: strconv.Itoa(i),
+		}
+		if err := s.Encode(&bb); err != nil {
+			t.Fatal(err)
+		}
+		block.Write(bb.Bytes()[:bb.Len()-len(avro.SnapshotOptionalFields)])
+	}
+
+	var sync [16]byte
+	var file bytes.Buffer
+	file.Write(containerMagic)
+	writeContainerLong(&file, 1)
+	writeContainerBytes(&file, []byte("avro.schema"))
+	writeContainerBytes(&file, legacySchema)
+	writeContainerLong(&file, 0)
+	file.Write(sync[:])
+	writeContainerLong(&file, int64(n))
+	writeContainerLong(&file, int64(block.Len()))
+	file.Write(block.Bytes())
+	file.Write(sync[:])
+	return file.Bytes()
+}
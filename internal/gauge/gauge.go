@@ -15,6 +15,19 @@ type Station struct {
 	Lg        float32 `firestore:"lng" json:"lng"`
 	Type      string  `firestore:"type" json:"type"`
 	Unit      string  `firestore:"unit" json:"unit"`
+
+	// optional hydrological statistics and catchment metadata, with
+	// nil or blank values where the source does not provide them
+	Datum         *float32 `firestore:"datum,omitempty" json:"datum,omitempty"`
+	CatchmentName string   `firestore:"catchment_name,omitempty" json:"catchment_name,omitempty"`
+	CatchmentArea *float32 `firestore:"catchment_area,omitempty" json:"catchment_area,omitempty"`
+	Lowest        *float32 `firestore:"lowest,omitempty" json:"lowest,omitempty"`
+	Low           *float32 `firestore:"low,omitempty" json:"low,omitempty"`
+	High          *float32 `firestore:"high,omitempty" json:"high,omitempty"`
+	Highest       *float32 `firestore:"highest,omitempty" json:"highest,omitempty"`
+	Mean          *float32 `firestore:"mean,omitempty" json:"mean,omitempty"`
+	InfoURL       string   `firestore:"info_url,omitempty" json:"info_url,omitempty"`
+	WiskiID       string   `firestore:"wiski_id,omitempty" json:"wiski_id,omitempty"`
 }
 
 // Reading is a point-in-time river gauge metric measurement
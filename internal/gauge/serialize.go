@@ -1,6 +1,7 @@
 package gauge
 
 import (
+	"bytes"
 	"io"
 	"time"
 
@@ -21,6 +22,20 @@ func typeToValue(t string) avro.TypeValues {
 	return avro.Level
 }
 
+func floatToAvro(f *float32) avro.UnionNullFloat {
+	if f == nil {
+		return avro.UnionNullFloat{UnionType: avro.UnionNullFloatTypeEnumNull}
+	}
+	return avro.UnionNullFloat{Float: *f, UnionType: avro.UnionNullFloatTypeEnumFloat}
+}
+
+func stringToAvro(s string) avro.UnionNullString {
+	if s == "" {
+		return avro.UnionNullString{UnionType: avro.UnionNullStringTypeEnumNull}
+	}
+	return avro.UnionNullString{String: s, UnionType: avro.UnionNullStringTypeEnumString}
+}
+
 func readingToAvro(r *Reading) *avro.Measure {
 	m := avro.NewMeasure()
 	m.Event_time = r.EventTime.Unix()
@@ -39,6 +54,16 @@ func snapshotToAvro(s *Snapshot) *avro.Snapshot {
 	a.Lg = s.Station.Lg
 	a.Type = typeToValue(s.Station.Type)
 	a.Unit = s.Station.Unit
+	a.Datum = floatToAvro(s.Station.Datum)
+	a.Catchment_name = stringToAvro(s.Station.CatchmentName)
+	a.Catchment_area = floatToAvro(s.Station.CatchmentArea)
+	a.Lowest = floatToAvro(s.Station.Lowest)
+	a.Low = floatToAvro(s.Station.Low)
+	a.High = floatToAvro(s.Station.High)
+	a.Highest = floatToAvro(s.Station.Highest)
+	a.Mean = floatToAvro(s.Station.Mean)
+	a.Info_url = stringToAvro(s.Station.InfoURL)
+	a.Wiski_id = stringToAvro(s.Station.WiskiID)
 	a.Correlation_id = s.CorrelationID
 	a.Causation_id = s.CausationID
 	a.Processed_time = s.ProcessedTime.Unix()
@@ -61,17 +86,42 @@ func (s *Snapshot) Encode(w io.Writer) error {
 	return nil
 }
 
+func avroToFloat(u avro.UnionNullFloat) *float32 {
+	if u.UnionType != avro.UnionNullFloatTypeEnumFloat {
+		return nil
+	}
+	f := u.Float
+	return &f
+}
+
+func avroToString(u avro.UnionNullString) string {
+	if u.UnionType != avro.UnionNullStringTypeEnumString {
+		return ""
+	}
+	return u.String
+}
+
 func avroToStation(a *avro.Snapshot) Station {
 	return Station{
-		DataURL:   a.Data_url,
-		AliasURL:  a.Alias_url,
-		HumanURL:  a.Human_url,
-		Name:      a.Name,
-		RiverName: a.River_name,
-		Lat:       a.Lat,
-		Lg:        a.Lg,
-		Type:      a.Type.String(),
-		Unit:      a.Unit,
+		DataURL:       a.Data_url,
+		AliasURL:      a.Alias_url,
+		HumanURL:      a.Human_url,
+		Name:          a.Name,
+		RiverName:     a.River_name,
+		Lat:           a.Lat,
+		Lg:            a.Lg,
+		Type:          a.Type.String(),
+		Unit:          a.Unit,
+		Datum:         avroToFloat(a.Datum),
+		CatchmentName: avroToString(a.Catchment_name),
+		CatchmentArea: avroToFloat(a.Catchment_area),
+		Lowest:        avroToFloat(a.Lowest),
+		Low:           avroToFloat(a.Low),
+		High:          avroToFloat(a.High),
+		Highest:       avroToFloat(a.Highest),
+		Mean:          avroToFloat(a.Mean),
+		InfoURL:       avroToString(a.Info_url),
+		WiskiID:       avroToString(a.Wiski_id),
 	}
 }
 
@@ -83,12 +133,21 @@ func avroToReading(a *avro.Measure) Reading {
 }
 
 // Decode reads a Snapshot from AVRO binary format
+//
+// Snapshots encoded before the optional fields were added to the schema
+// are padded with null values, so must be the only content in r.
 func (s *Snapshot) Decode(r io.Reader) error {
-	a, err := avro.DeserializeSnapshot(r)
+	nulls := bytes.NewReader(make([]byte, len(avro.SnapshotOptionalFields)))
+	a, err := avro.DeserializeSnapshot(io.MultiReader(r, nulls))
 	if err != nil {
 		return err
 	}
 
+	s.fromAvro(a)
+	return nil
+}
+
+func (s *Snapshot) fromAvro(a *avro.Snapshot) {
 	s.Station = avroToStation(a)
 	for _, m := range a.Readings {
 		s.Readings = append(s.Readings, avroToReading(m))
@@ -97,6 +156,4 @@ func (s *Snapshot) Decode(r io.Reader) error {
 	s.CorrelationID = a.Correlation_id
 	s.CausationID = a.Causation_id
 	s.ProcessedTime = time.Unix(a.Processed_time, 0)
-
-	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"testing"
 	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge/avro"
 )
 
 func TestEncodeDecode(t *testing.T) {
@@ -102,3 +104,72 @@ func TestEncodeDecode(t *testing.T) {
 		t.Error("CausationID mis-match", after)
 	}
 }
+
+func TestEncodeDecodeOptionalStatistics(t *testing.T) {
+	low, high := float32(0.168), float32(3.493)
+	before := Snapshot{
+		Station: Station{
+			DataURL:       "https://www2.sepa.org.uk/waterlevels/CSVs/10048-SG.csv",
+			Type:          "level",
+			CatchmentName: "Tay",
+			Low:           &low,
+			High:          &high,
+			InfoURL:       "http://www.ceh.ac.uk/data/nrfa/data/station.html?15042",
+		},
+		ProcessedTime: time.Unix(1451644200, 0),
+	}
+	var bb bytes.Buffer
+	if err := before.Encode(&bb); err != nil {
+		t.Fatal(err)
+	}
+	after := Snapshot{}
+	if err := after.Decode(&bb); err != nil {
+		t.Fatal(err)
+	}
+
+	if after.Station.Low == nil || *after.Station.Low != low {
+		t.Error("Low mis-match", after.Station.Low)
+	}
+	if after.Station.High == nil || *after.Station.High != high {
+		t.Error("High mis-match", after.Station.High)
+	}
+	if after.Station.Datum != nil || after.Station.Mean != nil {
+		t.Error("Unknown statistics should be nil", after.Station)
+	}
+	if after.Station.CatchmentName != before.Station.CatchmentName {
+		t.Error("Catchment name mis-match", after.Station.CatchmentName)
+	}
+	if after.Station.InfoURL != before.Station.InfoURL {
+		t.Error("Info URL mis-match", after.Station.InfoURL)
+	}
+}
+
+func TestDecodeBeforeOptionalFields(t *testing.T) {
+	before := Snapshot{
+		Station:       Station{DataURL: "rloi://1234", Type: "level", Unit: "m"},
+		Readings:      []Reading{{EventTime: time.Unix(1451644200, 0), Value: 1.23}},
+		CorrelationID: "ABCDE",
+		ProcessedTime: time.Unix(1451644200, 0),
+	}
+	var bb bytes.Buffer
+	if err := before.Encode(&bb); err != nil {
+		t.Fatal(err)
+	}
+	// the earlier schema is the same without the trailing optional
+	// fields, each of which is a single null union index byte
+	legacy := bb.Bytes()[:bb.Len()-len(avro.SnapshotOptionalFields)]
+
+	after := Snapshot{}
+	if err := after.Decode(bytes.NewReader(legacy)); err != nil {
+		t.Fatal(err)
+	}
+	if after.Station.DataURL != before.Station.DataURL || after.CorrelationID != before.CorrelationID {
+		t.Error("Snapshot mis-match", after)
+	}
+	if len(after.Readings) != 1 || after.Readings[0].Value != 1.23 {
+		t.Error("Readings mis-match", after.Readings)
+	}
+	if after.Station.Low != nil || after.Station.InfoURL != "" {
+		t.Error("Missing optional fields should be empty", after.Station)
+	}
+}
@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/report"
+)
+
+// DeadLetter is a message rejected by a subscriber, with the reason why
+//
+// The message bytes are kept exactly as received so they can be decoded
+// again once the cause, such as AVRO schema drift, has been fixed.
+type DeadLetter struct {
+	Reason       string    `json:"reason"`
+	RejectedTime time.Time `json:"rejected_time"`
+	Data         []byte    `json:"data"`
+}
+
+// Snapshot decodes the rejected message
+func (dl DeadLetter) Snapshot() (*gauge.Snapshot, error) {
+	s := gauge.Snapshot{}
+	err := s.Decode(bytes.NewBuffer(dl.Data))
+	return &s, err
+}
+
+// DeadLetterSink keeps rejected messages for later inspection
+type DeadLetterSink interface {
+	Reject(ctx context.Context, dl DeadLetter) error
+	Stop()
+}
+
+// DeadLetterTo sends messages that cannot be decoded to a sink, and allows
+// subscribers to reject snapshots they cannot handle
+func (t *Topic) DeadLetterTo(sink DeadLetterSink) {
+	t.deadLetters = sink
+}
+
+// Reject sends a snapshot to the dead-letter sink, if there is one
+func (t *Topic) Reject(ctx context.Context, s *gauge.Snapshot, reason string) report.Span {
+	span := report.StartSpan("snapshot.rejected").Field("station", s.Station.AliasURL).Field("reason", reason)
+	if t.deadLetters == nil {
+		return span.End()
+	}
+
+	bb := bytes.NewBuffer([]byte{})
+	if err := s.Encode(bb); err != nil {
+		return span.End(err)
+	}
+	return span.End(t.deadLetters.Reject(ctx, DeadLetter{
+		Reason:       reason,
+		RejectedTime: time.Now(),
+		Data:         bb.Bytes(),
+	}))
+}
+
+// Requeue publishes the original bytes of a dead-letter back to the topic
+func (t *Topic) Requeue(ctx context.Context, dl DeadLetter) report.Span {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	span := report.StartSpan("snapshot.requeued").Field("reason", dl.Reason)
+	return span.End(t.transport.Publish(ctx, dl.Data))
+}
+
+// DeadLetterConsumerGroup is the consumer group that drains dead-letters
+// from the topic
+const DeadLetterConsumerGroup = "deadletter"
+
+// subscriptionCreator is a transport that drops messages published before a
+// consumer group has subscribed, unless its subscription is created first
+type subscriptionCreator interface {
+	CreateSubscription(ctx context.Context, consumerGroup string) error
+}
+
+// DeadLetterTopic is a message queue topic of JSON encoded dead-letters
+type DeadLetterTopic struct {
+	transport Transport
+}
+
+// OpenDeadLetterTopic creates a dead-letter topic using the local file
+// transport if a directory is provided, otherwise using Pub/Sub
+//
+// The drain consumer group subscription is created straight away, so
+// dead-letters are kept even if they are rejected before it first runs.
+func OpenDeadLetterTopic(ctx context.Context, projectID string, dir string, topicName string) (*DeadLetterTopic, report.Span) {
+	topic, span := Open(ctx, projectID, dir, topicName)
+	if err := span.Err(); err != nil {
+		return nil, span
+	}
+	return newDeadLetterTopic(ctx, topic.transport, span)
+}
+
+func newDeadLetterTopic(ctx context.Context, transport Transport, span report.Span) (*DeadLetterTopic, report.Span) {
+	if sc, ok := transport.(subscriptionCreator); ok {
+		cSpan := report.StartSpan("subscription.created").Field("consumer_group", DeadLetterConsumerGroup)
+		span = span.Child(cSpan.End(sc.CreateSubscription(ctx, DeadLetterConsumerGroup)))
+		if err := span.Err(); err != nil {
+			transport.Stop()
+			return nil, span
+		}
+	}
+	return &DeadLetterTopic{transport: transport}, span
+}
+
+// Reject publishes a dead-letter to the topic
+func (t *DeadLetterTopic) Reject(ctx context.Context, dl DeadLetter) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	return t.transport.Publish(ctx, b)
+}
+
+// Subscribe reads dead-letters from the topic
+func (t *DeadLetterTopic) Subscribe(ctx context.Context, consumerGroup string,
+	fn func(ctx context.Context, dl DeadLetter) error) error {
+	return t.transport.Subscribe(ctx, consumerGroup, func(ctx context.Context, data []byte) error {
+		var dl DeadLetter
+		if err := json.Unmarshal(data, &dl); err != nil {
+			// keep the unreadable message itself as the dead-letter
+			dl = DeadLetter{Reason: "unreadable dead-letter: " + err.Error(), Data: data}
+		}
+		return fn(ctx, dl)
+	})
+}
+
+// Stop cleanly closes the topic
+func (t *DeadLetterTopic) Stop() {
+	t.transport.Stop()
+}
+
+// DeadLetterFile appends dead-letters to a local file as JSON lines
+type DeadLetterFile struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewDeadLetterFile creates a dead-letter file sink
+func NewDeadLetterFile(path string) (*DeadLetterFile, report.Span) {
+	span := report.StartSpan("deadletter.connected").Field("path", path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, span.End(err)
+	}
+
+	return &DeadLetterFile{Path: path}, span.End()
+}
+
+// Reject appends a dead-letter to the file
+func (f *DeadLetterFile) Reject(ctx context.Context, dl DeadLetter) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fh, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fh.Write(append(b, '\n')); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// Stop is a no-op as the file is closed after every write
+func (f *DeadLetterFile) Stop() {}
+
+// ReadAll reads every dead-letter in the file
+func (f *DeadLetterFile) ReadAll() ([]DeadLetter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fh, err := os.Open(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var dls []DeadLetter
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			return nil, err
+		}
+		dls = append(dls, dl)
+	}
+	return dls, scanner.Err()
+}
+
+// Replace atomically rewrites the file with only the given dead-letters
+func (f *DeadLetterFile) Replace(dls []DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var bb bytes.Buffer
+	for _, dl := range dls {
+		b, err := json.Marshal(dl)
+		if err != nil {
+			return err
+		}
+		bb.Write(b)
+		bb.WriteByte('\n')
+	}
+
+	tmp := f.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, bb.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/report"
+)
+
+func TestDeadLetterUndecodable(t *testing.T) {
+	dir := t.TempDir()
+	topic, _ := NewFile(dir, "gauge")
+	defer topic.Stop()
+	sink, span := NewDeadLetterFile(filepath.Join(dir, "deadletter.jsonl"))
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	topic.DeadLetterTo(sink)
+
+	// an undecodable message followed by a valid snapshot
+	if err := topic.transport.Publish(context.Background(), []byte{0xff}); err != nil {
+		t.Fatal(err)
+	}
+	publish(t, topic, "valid")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	nErrors := 0
+	err := topic.Subscribe(ctx, "store", func(ctx context.Context, err error, s *gauge.Snapshot) error {
+		if err != nil {
+			nErrors++
+			return nil
+		}
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nErrors != 1 {
+		t.Error("expected 1 decode error, got", nErrors)
+	}
+
+	dls, err := sink.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dls) != 1 || !strings.HasPrefix(dls[0].Reason, "decode:") || dls[0].Data[0] != 0xff {
+		t.Fatal("undecodable message not dead-lettered", dls)
+	}
+
+	if err := sink.Replace(nil); err != nil {
+		t.Fatal(err)
+	}
+	if dls, _ := sink.ReadAll(); len(dls) != 0 {
+		t.Error("dead-letters not replaced", dls)
+	}
+}
+
+func TestDeadLetterRejectAndRequeue(t *testing.T) {
+	dir := t.TempDir()
+	topic, _ := NewFile(dir, "gauge")
+	defer topic.Stop()
+	sink, span := OpenDeadLetterTopic(context.Background(), "", dir, "gauge-deadletter")
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+	topic.DeadLetterTo(sink)
+
+	s := &gauge.Snapshot{Station: gauge.Station{Name: "unrouted"}}
+	if err := topic.Reject(context.Background(), s, "no route").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var dl DeadLetter
+	err := sink.Subscribe(ctx, "inspect", func(ctx context.Context, received DeadLetter) error {
+		dl = received
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Reason != "no route" {
+		t.Fatal("dead-letter not received", dl)
+	}
+	if s, err := dl.Snapshot(); err != nil || s.Station.Name != "unrouted" {
+		t.Fatal("dead-letter not decoded", err, s)
+	}
+
+	if err := topic.Requeue(context.Background(), dl).Err(); err != nil {
+		t.Fatal(err)
+	}
+	snaps := receive(t, topic, "store", 1, nil)
+	if snaps[0].Station.Name != "unrouted" {
+		t.Error("dead-letter not requeued", snaps[0].Station)
+	}
+}
+
+// durableTransport records the subscriptions created ahead of any subscriber
+type durableTransport struct {
+	discard
+	created []string
+}
+
+func (t *durableTransport) CreateSubscription(ctx context.Context, consumerGroup string) error {
+	t.created = append(t.created, consumerGroup)
+	return nil
+}
+
+func TestDeadLetterSubscriptionCreatedOnOpen(t *testing.T) {
+	transport := &durableTransport{}
+	sink, span := newDeadLetterTopic(context.Background(), transport, report.StartSpan("topic.connected").End())
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	if len(transport.created) != 1 || transport.created[0] != DeadLetterConsumerGroup {
+		t.Error("drain subscription not created", transport.created)
+	}
+}
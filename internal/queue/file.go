@@ -0,0 +1,191 @@
+package queue
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robtuley/report"
+)
+
+// filePollInterval is how often subscribers check the log for new messages
+const filePollInterval = 250 * time.Millisecond
+
+// NewFile creates a message queue topic stored as an append-only log file
+// in a local directory
+//
+// Each message is written as a 4 byte big-endian length followed by the
+// message bytes. Consumer groups record their position in the log in an
+// offset file alongside it, so several processes on one machine can share
+// the topic.
+func NewFile(dir string, topicName string) (*Topic, report.Span) {
+	span := report.StartSpan("topic.connected").Field("topic_name", topicName).Field("transport", "file").Field("dir", dir)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, span.End(err)
+	}
+
+	return &Topic{
+		transport: &fileTransport{
+			Dir:  dir,
+			Name: topicName,
+		},
+	}, span.End()
+}
+
+// fileTransport carries messages in an append-only log file
+type fileTransport struct {
+	Dir  string
+	Name string
+	mu   sync.Mutex
+}
+
+func (t *fileTransport) logPath() string {
+	return filepath.Join(t.Dir, t.Name+".log")
+}
+
+func (t *fileTransport) offsetPath(consumerGroup string) string {
+	return filepath.Join(t.Dir, t.Name+"."+consumerGroup+".offset")
+}
+
+func (t *fileTransport) Stop() {}
+
+func (t *fileTransport) Publish(ctx context.Context, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := os.OpenFile(t.logPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	// write length and message in a single append so concurrent
+	// publishers from other processes cannot interleave
+	b := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(b, uint32(len(data)))
+	copy(b[4:], data)
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Note a zero length consumerGroup starts from the end of the log and does
+// not record its position, while a new named consumer group starts from the
+// beginning of the log.
+func (t *fileTransport) Subscribe(ctx context.Context, consumerGroup string,
+	fn func(ctx context.Context, data []byte) error) error {
+	offset, err := t.startOffset(consumerGroup)
+	if err != nil {
+		return err
+	}
+
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	for {
+		if f == nil {
+			f, err = os.Open(t.logPath())
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		var data []byte
+		if f != nil {
+			data, err = readMessageAt(f, offset)
+			if err != nil {
+				return err
+			}
+		}
+
+		if data == nil {
+			// wait for more messages to be published
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(filePollInterval):
+			}
+			continue
+		}
+
+		for fn(ctx, data) != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+			}
+		}
+
+		offset += int64(4 + len(data))
+		if consumerGroup != "" {
+			if err := t.saveOffset(consumerGroup, offset); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (t *fileTransport) startOffset(consumerGroup string) (int64, error) {
+	if consumerGroup == "" {
+		info, err := os.Stat(t.logPath())
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+
+	b, err := ioutil.ReadFile(t.offsetPath(consumerGroup))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func (t *fileTransport) saveOffset(consumerGroup string, offset int64) error {
+	path := t.offsetPath(consumerGroup)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readMessageAt reads the message at an offset in the log, or returns nil
+// if the message has not been completely written yet
+func readMessageAt(f *os.File, offset int64) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := f.ReadAt(header, offset); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := f.ReadAt(data, offset+4); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
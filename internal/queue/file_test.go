@@ -0,0 +1,42 @@
+package queue
+
+import (
+	"testing"
+)
+
+func TestFileTopic(t *testing.T) {
+	dir := t.TempDir()
+
+	publisher, span := NewFile(dir, "gauge")
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Stop()
+	subscriber, _ := NewFile(dir, "gauge")
+	defer subscriber.Stop()
+
+	// a new named consumer group reads from the start of the log
+	publish(t, publisher, "first", "second")
+	snaps := receive(t, subscriber, "store", 2, nil)
+	if snaps[0].Station.Name != "first" || snaps[1].Station.Name != "second" {
+		t.Error("snapshots out of order", snaps[0].Station, snaps[1].Station)
+	}
+
+	// and continues from where it left off
+	publish(t, publisher, "third")
+	snaps = receive(t, subscriber, "store", 1, nil)
+	if snaps[0].Station.Name != "third" {
+		t.Error("consumer group did not resume", snaps[0].Station)
+	}
+
+	// a temporary consumer group only reads new messages
+	ready := make(chan struct{})
+	go func() {
+		<-ready
+		publish(t, publisher, "fourth")
+	}()
+	snaps = receive(t, subscriber, "", 1, ready)
+	if snaps[0].Station.Name != "fourth" {
+		t.Error("temporary consumer group read old messages", snaps[0].Station)
+	}
+}
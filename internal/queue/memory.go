@@ -0,0 +1,126 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/robtuley/report"
+)
+
+// memoryBufferSize is the number of messages a consumer group can fall
+// behind before publishing blocks
+const memoryBufferSize = 1024
+
+// retryDelay is the wait before a failed message is redelivered
+const retryDelay = 250 * time.Millisecond
+
+var memoryTopics = struct {
+	sync.Mutex
+	byName map[string]*memoryTransport
+}{byName: make(map[string]*memoryTransport)}
+
+// NewMemory creates an in-process message queue topic
+//
+// Topics of the same name created within the same process share messages,
+// so publishers and subscribers can be connected without any other setup.
+func NewMemory(topicName string) (*Topic, report.Span) {
+	span := report.StartSpan("topic.connected").Field("topic_name", topicName).Field("transport", "memory")
+
+	memoryTopics.Lock()
+	defer memoryTopics.Unlock()
+
+	t, exists := memoryTopics.byName[topicName]
+	if !exists {
+		t = &memoryTransport{groups: make(map[string]*memoryGroup)}
+		memoryTopics.byName[topicName] = t
+	}
+
+	return &Topic{transport: t}, span.End()
+}
+
+// memoryTransport carries messages on channels, one per consumer group
+type memoryTransport struct {
+	mu     sync.Mutex
+	groups map[string]*memoryGroup
+	nTemp  int
+}
+
+// memoryGroup is the channel shared by the subscribers of a consumer group
+type memoryGroup struct {
+	ch           chan []byte
+	removedC     chan struct{} // closed once the last subscriber has gone
+	nSubscribers int
+}
+
+func (t *memoryTransport) Stop() {}
+
+func (t *memoryTransport) Publish(ctx context.Context, data []byte) error {
+	t.mu.Lock()
+	var groups []*memoryGroup
+	for _, g := range t.groups {
+		groups = append(groups, g)
+	}
+	t.mu.Unlock()
+
+	for _, g := range groups {
+		select {
+		case g.ch <- data:
+		case <-g.removedC:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Note messages are only delivered to a consumer group while at least one
+// subscriber for it exists, so the group is removed once its last
+// subscription ends and a zero length consumerGroup is never shared.
+func (t *memoryTransport) Subscribe(ctx context.Context, consumerGroup string,
+	fn func(ctx context.Context, data []byte) error) error {
+	t.mu.Lock()
+	if consumerGroup == "" {
+		t.nTemp++
+		consumerGroup = "temporary." + strconv.Itoa(t.nTemp)
+	}
+	g, exists := t.groups[consumerGroup]
+	if !exists {
+		g = &memoryGroup{
+			ch:       make(chan []byte, memoryBufferSize),
+			removedC: make(chan struct{}),
+		}
+		t.groups[consumerGroup] = g
+	}
+	g.nSubscribers++
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		g.nSubscribers--
+		if g.nSubscribers == 0 {
+			delete(t.groups, consumerGroup)
+			close(g.removedC)
+		}
+	}()
+	ch := g.ch
+
+	for {
+		var data []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case data = <-ch:
+		}
+
+		for fn(ctx, data) != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+// receive subscribes to a topic until n snapshots have been received
+func receive(t *testing.T, topic *Topic, consumerGroup string, n int, ready chan struct{}) []*gauge.Snapshot {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var snaps []*gauge.Snapshot
+	nFailures := 0
+	go func() {
+		if ready != nil {
+			// give the subscription time to be established
+			time.Sleep(50 * time.Millisecond)
+			close(ready)
+		}
+	}()
+	err := topic.Subscribe(ctx, consumerGroup, func(ctx context.Context, err error, s *gauge.Snapshot) error {
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		// fail the first delivery to check redelivery
+		if nFailures == 0 {
+			nFailures++
+			return errors.New("redeliver")
+		}
+		snaps = append(snaps, s)
+		if len(snaps) == n {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != n {
+		t.Fatal("expected", n, "snapshots but received", len(snaps))
+	}
+	return snaps
+}
+
+func publish(t *testing.T, topic *Topic, names ...string) {
+	for _, name := range names {
+		span := topic.Publish(context.Background(), &gauge.Snapshot{
+			Station: gauge.Station{Name: name},
+		})
+		if err := span.Err(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestMemoryTopic(t *testing.T) {
+	publisher, span := NewMemory("memory-test")
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Stop()
+	subscriber, _ := NewMemory("memory-test")
+	defer subscriber.Stop()
+
+	ready := make(chan struct{})
+	go func() {
+		<-ready
+		publish(t, publisher, "first", "second")
+	}()
+	snaps := receive(t, subscriber, "", 2, ready)
+	if snaps[0].Station.Name != "first" || snaps[1].Station.Name != "second" {
+		t.Error("snapshots out of order", snaps[0].Station, snaps[1].Station)
+	}
+	if snaps[0].CorrelationID == "" {
+		t.Error("no correlation ID", snaps[0])
+	}
+}
+
+func TestMemoryPublishAfterSubscriberExits(t *testing.T) {
+	topic, _ := NewMemory("memory-exit-test")
+	defer topic.Stop()
+
+	// a named group is kept only while it has a subscriber
+	ready := make(chan struct{})
+	go func() {
+		<-ready
+		publish(t, topic, "first")
+	}()
+	receive(t, topic, "store", 1, ready)
+
+	// publishing more than the buffer must not block once it has gone
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < memoryBufferSize+10; i++ {
+		if err := topic.transport.Publish(ctx, []byte("unread")); err != nil {
+			t.Fatal("publish blocked after subscriber exit", i, err)
+		}
+	}
+}
@@ -1,29 +1,17 @@
 package queue
 
 import (
-	"bytes"
 	"context"
 	"time"
 
 	"cloud.google.com/go/pubsub"
-	"github.com/robtuley/rainchasers/internal/gauge"
 	"github.com/robtuley/report"
 )
 
-// Topic encapsulates the message queue topic
-type Topic struct {
-	ProjectID string
-	pubSub    *pubsub.Topic
-}
-
-// Stop cleanly closes the topic
-func (t *Topic) Stop() {
-	if t.pubSub != nil {
-		t.pubSub.Stop()
-	}
-}
-
-// New creates a message queue topic
+// New creates a message queue topic on Google Pub/Sub
+//
+// A zero length projectID creates a topic that discards published messages
+// for use in dry runs.
 func New(ctx context.Context, projectID string, topicName string) (*Topic, report.Span) {
 	ctx, cancel := context.WithTimeout(ctx, 40*time.Second)
 	defer cancel()
@@ -31,7 +19,7 @@ func New(ctx context.Context, projectID string, topicName string) (*Topic, repor
 	span := report.StartSpan("topic.connected").Field("project_id", projectID).Field("topic_name", topicName)
 
 	if len(projectID) == 0 {
-		return &Topic{}, span.End()
+		return &Topic{transport: discard{}}, span.End()
 	}
 
 	client, err := pubsub.NewClient(ctx, projectID)
@@ -55,85 +43,54 @@ func New(ctx context.Context, projectID string, topicName string) (*Topic, repor
 
 	return &Topic{
 		ProjectID: projectID,
-		pubSub:    topic,
+		transport: &pubSubTransport{
+			ProjectID: projectID,
+			topic:     topic,
+		},
 	}, span.End()
 }
 
-// Publish writes an AVRO encoded Snapshot to the topic
-func (t *Topic) Publish(ctx context.Context, s *gauge.Snapshot) report.Span {
-	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
-	defer cancel()
-
-	span := report.StartSpan("snapshot.published")
-	span = span.Field("station", s.Station.AliasURL)
-	span = span.Field("count_readings", len(s.Readings))
-
-	if s.CorrelationID == "" {
-		// if there is no predefined existing trace info,
-		// assume this span tracer will be the trace generator
-		s.CorrelationID = span.TraceID()
-		s.CausationID = span.SpanID()
-	}
-	s.ProcessedTime = time.Now()
+// ackDeadline is how long a subscriber has to process a message before it is
+// redelivered
+const ackDeadline = time.Second * 20
 
-	bb := bytes.NewBuffer([]byte{})
-	err := s.Encode(bb)
-	if err != nil {
-		return span.End(err)
-	}
+// pubSubTransport carries messages on a Google Pub/Sub topic
+type pubSubTransport struct {
+	ProjectID string
+	topic     *pubsub.Topic
+}
 
-	if t.pubSub == nil {
-		return span.End()
-	}
+func (t *pubSubTransport) Stop() {
+	t.topic.Stop()
+}
 
-	result := t.pubSub.Publish(ctx, &pubsub.Message{
-		Data: bb.Bytes(),
+func (t *pubSubTransport) Publish(ctx context.Context, data []byte) error {
+	result := t.topic.Publish(ctx, &pubsub.Message{
+		Data: data,
 	})
-	_, err = result.Get(ctx)
-
-	return span.End(err)
+	_, err := result.Get(ctx)
+	return err
 }
 
-// Subscribe reads AVRO encoded snapshots from the topic and decodes them
-//
 // Note a zero length consumerGroup means auto-generate the pubsub subscription
 // string and delete once done.
-func (t *Topic) Subscribe(ctx context.Context, consumerGroup string,
-	fn func(ctx context.Context, err error, s *gauge.Snapshot) error) error {
-	const ackDeadline = time.Second * 20
-
-	if t.pubSub == nil {
-		// simulate a subscription i.e. wait for ctx cancel then return
-		<-ctx.Done()
-		return nil
-	}
-
+func (t *pubSubTransport) Subscribe(ctx context.Context, consumerGroup string,
+	fn func(ctx context.Context, data []byte) error) error {
 	isDeleteSubOnComplete := (consumerGroup == "")
 	if isDeleteSubOnComplete {
 		consumerGroup = time.Now().Format("v2006-01-02-15-04-05.999999")
 	}
-	subName := t.pubSub.ID() + "." + consumerGroup
+	subName := t.topic.ID() + "." + consumerGroup
 
 	client, err := pubsub.NewClient(ctx, t.ProjectID)
 	if err != nil {
 		return err
 	}
 
-	sub := client.Subscription(subName)
-	exists, err := sub.Exists(ctx)
+	sub, err := t.subscription(ctx, client, subName)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		cfg := pubsub.SubscriptionConfig{
-			Topic:       t.pubSub,
-			AckDeadline: ackDeadline,
-		}
-		sub, err = client.CreateSubscription(ctx, subName, cfg)
-		if err != nil {
-			return err
-		}
-	}
 	if isDeleteSubOnComplete {
 		defer sub.Delete(context.Background())
 	}
@@ -142,9 +99,7 @@ func (t *Topic) Subscribe(ctx context.Context, consumerGroup string,
 		ctx, cancel := context.WithTimeout(ctx, ackDeadline-time.Second)
 		defer cancel()
 
-		s := gauge.Snapshot{}
-		err := s.Decode(bytes.NewBuffer(m.Data))
-		err = fn(ctx, err, &s)
+		err := fn(ctx, m.Data)
 		if err != nil {
 			m.Nack() // speed up message redelivery
 			return
@@ -152,3 +107,35 @@ func (t *Topic) Subscribe(ctx context.Context, consumerGroup string,
 		m.Ack()
 	})
 }
+
+// CreateSubscription creates the subscription of a consumer group if it does
+// not already exist, so messages published before the first subscriber
+// connects are kept rather than dropped
+func (t *pubSubTransport) CreateSubscription(ctx context.Context, consumerGroup string) error {
+	client, err := pubsub.NewClient(ctx, t.ProjectID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = t.subscription(ctx, client, t.topic.ID()+"."+consumerGroup)
+	return err
+}
+
+// subscription gets a subscription to the topic, creating it if necessary
+func (t *pubSubTransport) subscription(ctx context.Context, client *pubsub.Client, subName string) (*pubsub.Subscription, error) {
+	sub := client.Subscription(subName)
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return sub, nil
+	}
+
+	cfg := pubsub.SubscriptionConfig{
+		Topic:       t.topic,
+		AckDeadline: ackDeadline,
+	}
+	return client.CreateSubscription(ctx, subName, cfg)
+}
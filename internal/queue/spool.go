@@ -0,0 +1,195 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robtuley/report"
+)
+
+const (
+	// DefaultSpoolMaxBytes bounds the disk used by a spool
+	DefaultSpoolMaxBytes = 64 * 1024 * 1024
+	// DefaultSpoolMaxAge is how long a spooled message is kept, by
+	// which time the readings have little value to the store
+	DefaultSpoolMaxAge = 24 * time.Hour
+	// DefaultSpoolMaxReplay bounds the messages replayed at a time, so
+	// a spool built up over a long outage drains gradually
+	DefaultSpoolMaxReplay = 100
+)
+
+// Spool is an on-disk outbox of encoded messages that failed to publish
+//
+// Each message is a file named by the time it was spooled, so they are
+// replayed in the order they were originally published. The oldest
+// messages are evicted once they exceed MaxAge, or once the spool grows
+// beyond MaxBytes. At most MaxReplay messages are replayed at a time.
+type Spool struct {
+	Dir       string
+	MaxBytes  int64
+	MaxAge    time.Duration
+	MaxReplay int
+	mu        sync.Mutex
+	seq       int
+}
+
+// NewSpool creates a spool in a local directory
+func NewSpool(dir string) (*Spool, report.Span) {
+	span := report.StartSpan("spool.connected").Field("dir", dir)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, span.End(err)
+	}
+
+	return &Spool{
+		Dir:       dir,
+		MaxBytes:  DefaultSpoolMaxBytes,
+		MaxAge:    DefaultSpoolMaxAge,
+		MaxReplay: DefaultSpoolMaxReplay,
+	}, span.End()
+}
+
+// SpoolTo keeps messages that fail to publish in a spool directory, and
+// replays them once publishing recovers
+func (t *Topic) SpoolTo(dir string) report.Span {
+	spool, span := NewSpool(dir)
+	if err := span.Err(); err != nil {
+		return span
+	}
+	t.spool = spool
+	return span
+}
+
+// Add writes a message to the spool, returning the number of older
+// messages evicted to make room for it
+func (s *Spool) Add(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d.avro", time.Now().UnixNano(), s.seq%1000000)
+
+	// write to a hidden temporary file first so a partial message
+	// is never replayed
+	f, err := ioutil.TempFile(s.Dir, ".tmp-")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return 0, err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(s.Dir, name)); err != nil {
+		os.Remove(f.Name())
+		return 0, err
+	}
+
+	return s.evict()
+}
+
+// Len is the number of messages in the spool
+func (s *Spool) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.list()
+	return len(files), err
+}
+
+// Replay publishes up to MaxReplay spooled messages oldest first, removing
+// each once it has been published, and stops at the first publish error.
+// It returns the number replayed and the number still left in the spool.
+func (s *Spool) Replay(ctx context.Context, publish func(ctx context.Context, data []byte) error) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.evict(); err != nil {
+		return 0, 0, err
+	}
+	files, err := s.list()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	n := 0
+	for _, fi := range files {
+		if s.MaxReplay > 0 && n >= s.MaxReplay {
+			break
+		}
+		path := filepath.Join(s.Dir, fi.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return n, len(files) - n, err
+		}
+		if err := publish(ctx, data); err != nil {
+			return n, len(files) - n, err
+		}
+		if err := os.Remove(path); err != nil {
+			return n, len(files) - n, err
+		}
+		n++
+	}
+	return n, len(files) - n, nil
+}
+
+// evict removes messages older than MaxAge, then the oldest messages
+// until the spool is within MaxBytes
+func (s *Spool) evict() (int, error) {
+	files, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, fi := range files {
+		total += fi.Size()
+	}
+
+	n := 0
+	cutoff := time.Now().Add(-s.MaxAge)
+	for _, fi := range files {
+		isTooOld := s.MaxAge > 0 && fi.ModTime().Before(cutoff)
+		isTooBig := s.MaxBytes > 0 && total > s.MaxBytes
+		if !isTooOld && !isTooBig {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.Dir, fi.Name())); err != nil {
+			return n, err
+		}
+		total -= fi.Size()
+		n++
+	}
+	return n, nil
+}
+
+// list returns the spooled messages, oldest first
+func (s *Spool) list() ([]os.FileInfo, error) {
+	all, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(all))
+	for _, fi := range all {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		files = append(files, fi)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	return files, nil
+}
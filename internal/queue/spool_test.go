@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// outage is a transport that fails to publish while down
+type outage struct {
+	Transport
+	down bool
+}
+
+func (o *outage) Publish(ctx context.Context, data []byte) error {
+	if o.down {
+		return errors.New("unavailable")
+	}
+	return o.Transport.Publish(ctx, data)
+}
+
+func TestSpoolReplaysInOrder(t *testing.T) {
+	dir := t.TempDir()
+	publisher, _ := NewFile(dir, "gauge")
+	defer publisher.Stop()
+	subscriber, _ := NewFile(dir, "gauge")
+	defer subscriber.Stop()
+
+	o := &outage{Transport: publisher.transport}
+	publisher.transport = o
+	if err := publisher.SpoolTo(filepath.Join(dir, "spool")).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	publish(t, publisher, "first")
+	o.down = true
+	publish(t, publisher, "second", "third")
+	if n, _ := publisher.spool.Len(); n != 2 {
+		t.Fatal("expected 2 spooled snapshots, got", n)
+	}
+
+	o.down = false
+	publish(t, publisher, "fourth")
+	if n, _ := publisher.spool.Len(); n != 0 {
+		t.Error("spool not emptied on recovery", n)
+	}
+
+	snaps := receive(t, subscriber, "store", 4, nil)
+	for i, name := range []string{"first", "second", "third", "fourth"} {
+		if snaps[i].Station.Name != name {
+			t.Error("expected", name, "at", i, "but got", snaps[i].Station.Name)
+		}
+	}
+}
+
+func TestSpoolEviction(t *testing.T) {
+	spool, span := NewSpool(t.TempDir())
+	if err := span.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// bounded size evicts the oldest messages
+	spool.MaxBytes = 10
+	for _, data := range []string{"aaaa", "bbbb", "cccc"} {
+		if _, err := spool.Add([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var replayed []string
+	n, _, err := spool.Replay(context.Background(), func(ctx context.Context, data []byte) error {
+		replayed = append(replayed, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || replayed[0] != "bbbb" || replayed[1] != "cccc" {
+		t.Error("oldest message not evicted", replayed)
+	}
+
+	// messages beyond the maximum age are evicted
+	spool.MaxAge = time.Hour
+	if _, err := spool.Add([]byte("dddd")); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := spool.list()
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(spool.Dir, files[0].Name()), old, old)
+	n, _, _ = spool.Replay(context.Background(), func(ctx context.Context, data []byte) error {
+		t.Error("expired message replayed", string(data))
+		return nil
+	})
+	if n != 0 {
+		t.Error("expected no messages replayed, got", n)
+	}
+}
+
+func TestSpoolDrainsInBatches(t *testing.T) {
+	dir := t.TempDir()
+	publisher, _ := NewFile(dir, "gauge")
+	defer publisher.Stop()
+	subscriber, _ := NewFile(dir, "gauge")
+	defer subscriber.Stop()
+
+	o := &outage{Transport: publisher.transport}
+	publisher.transport = o
+	if err := publisher.SpoolTo(filepath.Join(dir, "spool")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	publisher.spool.MaxReplay = 2
+
+	o.down = true
+	publish(t, publisher, "first", "second", "third", "fourth", "fifth")
+
+	// each publish replays a batch, queueing behind what is left
+	o.down = false
+	publish(t, publisher, "sixth")
+	if n, _ := publisher.spool.Len(); n != 4 {
+		t.Fatal("expected 4 spooled snapshots, got", n)
+	}
+	publish(t, publisher, "seventh")
+	publish(t, publisher, "eighth")
+	if n, _ := publisher.spool.Len(); n != 2 {
+		t.Fatal("expected 2 spooled snapshots, got", n)
+	}
+	publish(t, publisher, "ninth")
+	if n, _ := publisher.spool.Len(); n != 0 {
+		t.Error("spool not emptied", n)
+	}
+
+	names := []string{"first", "second", "third", "fourth", "fifth",
+		"sixth", "seventh", "eighth", "ninth"}
+	snaps := receive(t, subscriber, "store", len(names), nil)
+	for i, name := range names {
+		if snaps[i].Station.Name != name {
+			t.Error("expected", name, "at", i, "but got", snaps[i].Station.Name)
+		}
+	}
+}
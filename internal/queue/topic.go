@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+	"github.com/robtuley/report"
+)
+
+// Transport carries encoded messages for a topic
+type Transport interface {
+	// Publish sends a message to all consumer groups
+	Publish(ctx context.Context, data []byte) error
+	// Subscribe calls fn for each message received by the consumer group
+	// until ctx is cancelled, redelivering the message if fn returns an error
+	Subscribe(ctx context.Context, consumerGroup string, fn func(ctx context.Context, data []byte) error) error
+	// Stop releases any resources
+	Stop()
+}
+
+// Topic encapsulates the message queue topic
+type Topic struct {
+	ProjectID   string
+	transport   Transport
+	spool       *Spool
+	deadLetters DeadLetterSink
+}
+
+// Stop cleanly closes the topic
+func (t *Topic) Stop() {
+	t.transport.Stop()
+}
+
+// Open creates a message queue topic using the local file transport if a
+// directory is provided, otherwise using Pub/Sub
+func Open(ctx context.Context, projectID string, dir string, topicName string) (*Topic, report.Span) {
+	if dir != "" {
+		return NewFile(dir, topicName)
+	}
+	return New(ctx, projectID, topicName)
+}
+
+// spoolReplayTimeout bounds the time spent replaying spooled snapshots
+// ahead of each publish, separate from the time allowed for the publish
+const spoolReplayTimeout = 20 * time.Second
+
+// Publish writes an AVRO encoded Snapshot to the topic
+//
+// If the topic has a spool, a snapshot that fails to publish is kept on
+// disk and the span ends without error, and spooled snapshots are replayed
+// a batch at a time ahead of each publish. While the spool is still being
+// drained new snapshots join the back of it, so they stay in order.
+func (t *Topic) Publish(ctx context.Context, s *gauge.Snapshot) report.Span {
+	span := report.StartSpan("snapshot.published")
+	span = span.Field("station", s.Station.AliasURL)
+	span = span.Field("count_readings", len(s.Readings))
+
+	if s.CorrelationID == "" {
+		// if there is no predefined existing trace info,
+		// assume this span tracer will be the trace generator
+		s.CorrelationID = span.TraceID()
+		s.CausationID = span.SpanID()
+	}
+	s.ProcessedTime = time.Now()
+
+	bb := bytes.NewBuffer([]byte{})
+	err := s.Encode(bb)
+	if err != nil {
+		return span.End(err)
+	}
+
+	if t.spool == nil {
+		publishCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+		defer cancel()
+		return span.End(t.transport.Publish(publishCtx, bb.Bytes()))
+	}
+
+	// replay any earlier failures first so snapshots stay in order
+	replayCtx, cancelReplay := context.WithTimeout(ctx, spoolReplayTimeout)
+	n, remaining, err := t.spool.Replay(replayCtx, t.transport.Publish)
+	cancelReplay()
+	if n > 0 {
+		span = span.Field("count_replayed", n)
+	}
+	if err != nil {
+		return t.spoolSnapshot(span.Field("publish_error", err.Error()), bb.Bytes(), err)
+	}
+	if remaining > 0 {
+		// queue behind the snapshots still to be replayed
+		return t.spoolSnapshot(span.Field("count_spool_remaining", remaining), bb.Bytes(), nil)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	err = t.transport.Publish(publishCtx, bb.Bytes())
+	if err != nil {
+		return t.spoolSnapshot(span.Field("publish_error", err.Error()), bb.Bytes(), err)
+	}
+	return span.End()
+}
+
+// spoolSnapshot keeps an encoded snapshot to publish once the topic
+// recovers, only ending the span with the publish error if it could
+// not be spooled
+func (t *Topic) spoolSnapshot(span report.Span, data []byte, publishErr error) report.Span {
+	evicted, err := t.spool.Add(data)
+	if err != nil {
+		span = span.Field("spool_error", err.Error())
+		if publishErr != nil {
+			return span.End(publishErr)
+		}
+		return span.End(err)
+	}
+	span = span.Field("spooled", true)
+	if evicted > 0 {
+		span = span.Field("count_evicted", evicted)
+	}
+	return span.End()
+}
+
+// Subscribe reads AVRO encoded snapshots from the topic and decodes them
+//
+// Note a zero length consumerGroup means a temporary subscription that only
+// receives messages published while it is subscribed. Messages that cannot
+// be decoded are sent to the dead-letter sink, if there is one, before fn is
+// called with the decode error.
+func (t *Topic) Subscribe(ctx context.Context, consumerGroup string,
+	fn func(ctx context.Context, err error, s *gauge.Snapshot) error) error {
+	return t.transport.Subscribe(ctx, consumerGroup, func(ctx context.Context, data []byte) error {
+		s := gauge.Snapshot{}
+		err := s.Decode(bytes.NewBuffer(data))
+		if err != nil && t.deadLetters != nil {
+			dlErr := t.deadLetters.Reject(ctx, DeadLetter{
+				Reason:       "decode: " + err.Error(),
+				RejectedTime: time.Now(),
+				Data:         data,
+			})
+			if dlErr != nil {
+				// redeliver rather than lose the message
+				return dlErr
+			}
+		}
+		return fn(ctx, err, &s)
+	})
+}
+
+// discard is a transport that drops published messages, and whose
+// subscriptions simply wait for the context to be cancelled
+type discard struct{}
+
+func (discard) Publish(ctx context.Context, data []byte) error {
+	return nil
+}
+
+func (discard) Subscribe(ctx context.Context, consumerGroup string, fn func(ctx context.Context, data []byte) error) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (discard) Stop() {}
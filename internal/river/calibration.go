@@ -1,16 +1,44 @@
 package river
 
+import "time"
+
+// DefaultCalibrationType is the measurement type of a calibration that
+// does not specify one
+const DefaultCalibrationType = "level"
+
 // Calibration is a referenced gauge related to a section
 type Calibration struct {
 	URL         string `firestore:"data_url"`
 	Description string `firestore:"desc"`
 
+	// Type is the measurement type calibrated, such as level or flow, and
+	// Unit the unit of the minimum values, with a blank unit taken to be the
+	// same as the gauge
+	Type string `firestore:"type,omitempty"`
+	Unit string `firestore:"unit,omitempty"`
+
 	// Minimum is a map of the minimum values for each level
 	//
 	// Note that because we need to write this map to and from firestore
 	// we cannot use the native Level type as the key; instead we convert
 	// to/from a string.
 	Minimum map[string]float32 `firestore:"minimum"`
+
+	// Derived computes the gauge readings from other gauges, in which case
+	// the URL identifies the derived gauge
+	Derived Derivation `firestore:"derived,omitempty"`
+
+	// Window is the period rainfall is accumulated over for a calibration
+	// of rainfall type, with the minimum values in total rainfall
+	Window time.Duration `firestore:"window,omitempty"`
+
+	// Lag is the travel time from the gauge to the section, positive for a
+	// gauge upstream and negative for a gauge downstream
+	Lag time.Duration `firestore:"lag,omitempty"`
+
+	// Estimate links a gauge that has no thresholds, so the level can only
+	// be approximated from the typical range of the gauge
+	Estimate bool `firestore:"estimate,omitempty"`
 }
 
 // LevelAt provides the level state at a certain reading
@@ -31,3 +59,30 @@ func (c Calibration) LevelAt(value float32) Level {
 
 	return state
 }
+
+// For checks the calibration applies to a gauge measurement type and unit,
+// returning a calibration with the minimum values converted to the gauge
+// unit if it does
+func (c Calibration) For(gaugeType string, gaugeUnit string) (Calibration, bool) {
+	calType := c.Type
+	if calType == "" {
+		calType = DefaultCalibrationType
+	}
+	if calType != gaugeType {
+		return c, false
+	}
+	if c.Unit == "" || c.Unit == gaugeUnit {
+		return c, true
+	}
+	if _, ok := ConvertUnit(0, c.Unit, gaugeUnit); !ok {
+		return c, false
+	}
+
+	converted := c
+	converted.Unit = gaugeUnit
+	converted.Minimum = make(map[string]float32, len(c.Minimum))
+	for lvl, minValue := range c.Minimum {
+		converted.Minimum[lvl], _ = ConvertUnit(minValue, c.Unit, gaugeUnit)
+	}
+	return converted, true
+}
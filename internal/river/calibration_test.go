@@ -27,3 +27,49 @@ func TestLevelAt(t *testing.T) {
 		}
 	}
 }
+
+func TestEstimateAt(t *testing.T) {
+	expect := map[float32]Level{
+		0.05: Empty,
+		0.3:  Scrape,
+		0.5:  Low,
+		0.7:  Medium,
+		0.9:  High,
+		2.5:  High,
+	}
+	for value, lvl := range expect {
+		if state := EstimateAt(value, 0.1, 0.9); state != lvl {
+			t.Error(value, "has estimated level", state, "not", lvl)
+		}
+	}
+
+	if state := EstimateAt(0.5, 0.9, 0.1); state != Unknown {
+		t.Error("inverted range has estimated level", state)
+	}
+}
+
+func TestCalibrationFor(t *testing.T) {
+	c := Calibration{
+		Unit:    "m",
+		Minimum: map[string]float32{Low.String(): 0.5},
+	}
+
+	if _, ok := c.For("flow", "m3/s"); ok {
+		t.Error("Level calibration used for flow")
+	}
+	if _, ok := c.For("level", "m3/s"); ok {
+		t.Error("Metre calibration used for cumecs")
+	}
+	converted, ok := c.For("level", "mm")
+	if !ok || converted.Unit != "mm" || converted.Minimum[Low.String()] != 500 {
+		t.Error("Calibration not converted to mm", converted)
+	}
+	if c.Minimum[Low.String()] != 0.5 {
+		t.Error("Original calibration modified", c)
+	}
+
+	flow := Calibration{Type: "flow"}
+	if _, ok := flow.For("flow", "m3/s"); !ok {
+		t.Error("Flow calibration without unit not used for flow")
+	}
+}
@@ -0,0 +1,56 @@
+package river
+
+import "sync"
+
+// Catalogue looks up sections by slug, including the previous slugs of
+// renamed sections, and can be updated while in use as content reloads
+type Catalogue struct {
+	mu      sync.RWMutex
+	all     []Section
+	bySlug  map[string]Section
+	byAlias map[string]Section
+}
+
+// NewCatalogue creates a catalogue of the content sections
+func NewCatalogue(content Content) *Catalogue {
+	c := &Catalogue{}
+	c.Update(content)
+	return c
+}
+
+// Update replaces the sections with new content
+func (c *Catalogue) Update(content Content) {
+	bySlug := make(map[string]Section, len(content.Sections))
+	byAlias := make(map[string]Section)
+	for _, s := range content.Sections {
+		bySlug[s.Slug] = s
+		for _, alias := range s.Aliases {
+			byAlias[alias] = s
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.all = content.Sections
+	c.bySlug = bySlug
+	c.byAlias = byAlias
+}
+
+// Find looks up the section with a slug, or that used to have the slug
+// before it was renamed, in which case isAlias is true
+func (c *Catalogue) Find(slug string) (s Section, isAlias bool, exists bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if s, exists := c.bySlug[slug]; exists {
+		return s, false, true
+	}
+	s, exists = c.byAlias[slug]
+	return s, exists, exists
+}
+
+// All is every section
+func (c *Catalogue) All() []Section {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.all
+}
@@ -0,0 +1,25 @@
+package river
+
+import "testing"
+
+func TestCatalogueFindsAliases(t *testing.T) {
+	s := validSection()
+	s.Aliases = []string{"vyrnwy-old-name"}
+	c := NewCatalogue(Content{Sections: []Section{s}})
+
+	if found, isAlias, exists := c.Find(s.Slug); !exists || isAlias || found.UUID != s.UUID {
+		t.Error("Section not found by slug", found, isAlias, exists)
+	}
+	if found, isAlias, exists := c.Find("vyrnwy-old-name"); !exists || !isAlias || found.Slug != s.Slug {
+		t.Error("Section not found by alias", found, isAlias, exists)
+	}
+	if _, _, exists := c.Find("unknown"); exists {
+		t.Error("Unknown slug found")
+	}
+
+	// reloaded content replaces the sections
+	c.Update(Content{})
+	if _, _, exists := c.Find(s.Slug); exists || len(c.All()) != 0 {
+		t.Error("Sections not replaced", c.All())
+	}
+}
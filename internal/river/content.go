@@ -0,0 +1,297 @@
+package river
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	// release calendars are in UK local time, which must be parsed even
+	// where the host has no timezone database
+	_ "time/tzdata"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Content is the river sections along with the gauges and release
+// calendars linked to them, in section uuid keyed maps
+type Content struct {
+	Sections     []Section
+	Calibrations map[string][]Calibration
+	Releases     map[string]Calendar
+}
+
+// ReleaseZone is the local time of the published release calendars
+const ReleaseZone = "Europe/London"
+
+type yamlCalibration struct {
+	URL         string   `yaml:"data_url"`
+	Description string   `yaml:"desc"`
+	Type        string   `yaml:"type,omitempty"`
+	Unit        string   `yaml:"unit,omitempty"`
+	Scrape      *float32 `yaml:"scrape,omitempty"`
+	Low         *float32 `yaml:"low,omitempty"`
+	Medium      *float32 `yaml:"medium,omitempty"`
+	High        *float32 `yaml:"high,omitempty"`
+	Huge        *float32 `yaml:"huge,omitempty"`
+	TooHigh     *float32 `yaml:"toohigh,omitempty"`
+	Lag         string   `yaml:"lag,omitempty"`
+	Window      string   `yaml:"window,omitempty"`
+	Estimate    bool     `yaml:"estimate,omitempty"`
+
+	Derived Derivation `yaml:"derived,omitempty"`
+}
+
+// yamlLinks are the parts of a river file linking the section to gauges
+type yamlLinks struct {
+	Measures []yamlCalibration `yaml:"measures"`
+}
+
+// yamlMeasureKeys is the keys used in each measure, as a misspelt threshold
+// is otherwise silently ignored when decoding a yamlCalibration
+type yamlMeasureKeys struct {
+	Measures []map[string]interface{} `yaml:"measures"`
+}
+
+// yamlCalibrationKeys are the keys a measure can have
+var yamlCalibrationKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(yamlCalibration{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		keys[name] = true
+	}
+	return keys
+}()
+
+type yamlRelease struct {
+	Date  string  `yaml:"date"`
+	From  string  `yaml:"from"`
+	To    string  `yaml:"to"`
+	Flow  float32 `yaml:"flow,omitempty"`
+	Level string  `yaml:"level,omitempty"`
+}
+
+type yamlCalendar struct {
+	Description string        `yaml:"desc"`
+	URL         string        `yaml:"url,omitempty"`
+	Releases    []yamlRelease `yaml:"releases"`
+}
+
+// LoadDir parses the content of the rivers/*.yaml and releases/*.yaml files
+// in a directory, along with the problems found keyed by file name
+//
+// The error is only set if the files cannot be read, so the problems must
+// be checked for any that are fatal before the content is used.
+func LoadDir(dir string) (Content, map[string][]Problem, error) {
+	c, problems, _, err := loadDir(dir)
+	return c, problems, err
+}
+
+// loadDir also returns a checksum of the files so changes can be detected
+func loadDir(dir string) (Content, map[string][]Problem, string, error) {
+	content := Content{
+		Calibrations: make(map[string][]Calibration),
+		Releases:     make(map[string]Calendar),
+	}
+	problems := make(map[string][]Problem)
+	hash := sha256.New()
+	add := func(fn string, ps ...Problem) {
+		if len(ps) > 0 {
+			problems[fn] = append(problems[fn], ps...)
+		}
+	}
+
+	riverFiles, err := filepath.Glob(filepath.Join(dir, "rivers", "*.yaml"))
+	if err != nil {
+		return content, problems, "", err
+	}
+	slugFiles := make(map[string]string)
+
+nextFile:
+	for _, fn := range riverFiles {
+		y, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return content, problems, "", err
+		}
+		hash.Write([]byte(filepath.Base(fn)))
+		hash.Write(y)
+		fn = relativeTo(dir, fn)
+
+		// parse a section from yaml
+		var s Section
+		var links yamlLinks
+		var keys yamlMeasureKeys
+		if err := yaml.Unmarshal(y, &s); err != nil {
+			add(fn, fatal("yaml", err.Error()))
+			continue nextFile
+		}
+		if err := yaml.Unmarshal(y, &links); err != nil {
+			add(fn, fatal("yaml", err.Error()))
+			continue nextFile
+		}
+		if err := yaml.Unmarshal(y, &keys); err != nil {
+			add(fn, fatal("yaml", err.Error()))
+			continue nextFile
+		}
+		s.Slug = strings.TrimSuffix(filepath.Base(fn), ".yaml")
+		content.Sections = append(content.Sections, s)
+		slugFiles[s.Slug] = fn
+
+		// parse the calibrations
+		for i, yc := range links.Measures {
+			c, ps := yc.toCalibration()
+			add(fn, ps...)
+			add(fn, unknownKeys(c.URL, keys.Measures[i])...)
+			add(fn, c.Validate()...)
+			content.Calibrations[s.UUID] = append(content.Calibrations[s.UUID], c)
+		}
+	}
+	for slug, ps := range ValidateSections(content.Sections) {
+		add(slugFiles[slug], ps...)
+	}
+
+	// release calendars are named after the slug of their section
+	releaseFiles, err := filepath.Glob(filepath.Join(dir, "releases", "*.yaml"))
+	if err != nil {
+		return content, problems, "", err
+	}
+	for _, fn := range releaseFiles {
+		y, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return content, problems, "", err
+		}
+		hash.Write([]byte(filepath.Base(fn)))
+		hash.Write(y)
+		fn = relativeTo(dir, fn)
+
+		slug := strings.TrimSuffix(filepath.Base(fn), ".yaml")
+		uuid := ""
+		for _, s := range content.Sections {
+			if s.Slug == slug {
+				uuid = s.UUID
+			}
+		}
+		if uuid == "" {
+			add(fn, fatal("slug", "is not a section: "+slug))
+			continue
+		}
+
+		var yc yamlCalendar
+		if err := yaml.Unmarshal(y, &yc); err != nil {
+			add(fn, fatal("yaml", err.Error()))
+			continue
+		}
+		c, ps := yc.toCalendar()
+		add(fn, ps...)
+		add(fn, c.Validate()...)
+		content.Releases[uuid] = c
+	}
+
+	return content, problems, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// relativeTo names a file relative to the content directory
+func relativeTo(dir string, fn string) string {
+	if rel, err := filepath.Rel(dir, fn); err == nil {
+		return rel
+	}
+	return fn
+}
+
+// unknownKeys reports any key of a measure that is not a calibration field
+func unknownKeys(url string, measure map[string]interface{}) []Problem {
+	var unknown []string
+	for key := range measure {
+		if !yamlCalibrationKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	var problems []Problem
+	for _, key := range unknown {
+		problems = append(problems, fatal(url+" "+key, "is not a known field"))
+	}
+	return problems
+}
+
+func (yc yamlCalibration) toCalibration() (Calibration, []Problem) {
+	c := Calibration{
+		URL:         yc.URL,
+		Description: yc.Description,
+		Type:        yc.Type,
+		Unit:        yc.Unit,
+		Minimum:     make(map[string]float32),
+		Estimate:    yc.Estimate,
+		Derived:     yc.Derived,
+	}
+	for lvl, v := range map[Level]*float32{
+		Scrape:  yc.Scrape,
+		Low:     yc.Low,
+		Medium:  yc.Medium,
+		High:    yc.High,
+		Huge:    yc.Huge,
+		TooHigh: yc.TooHigh,
+	} {
+		if v != nil {
+			c.Minimum[lvl.String()] = *v
+		}
+	}
+
+	var problems []Problem
+	var err error
+	if yc.Window != "" {
+		c.Window, err = time.ParseDuration(yc.Window)
+		if err != nil {
+			problems = append(problems, fatal(c.URL+" window", "is not a duration: "+yc.Window))
+		}
+	}
+	if yc.Lag != "" {
+		c.Lag, err = time.ParseDuration(yc.Lag)
+		if err != nil {
+			problems = append(problems, fatal(c.URL+" lag", "is not a duration: "+yc.Lag))
+		}
+	}
+	return c, problems
+}
+
+func (yc yamlCalendar) toCalendar() (Calendar, []Problem) {
+	zone, err := time.LoadLocation(ReleaseZone)
+	if err != nil {
+		return Calendar{}, []Problem{fatal("releases", err.Error())}
+	}
+
+	var problems []Problem
+	c := Calendar{
+		Description: yc.Description,
+		URL:         yc.URL,
+	}
+	for _, yr := range yc.Releases {
+		start, err := time.ParseInLocation("2006-01-02 15:04", yr.Date+" "+yr.From, zone)
+		if err != nil {
+			problems = append(problems, fatal("releases", "invalid start "+yr.Date+" "+yr.From))
+			continue
+		}
+		end, err := time.ParseInLocation("2006-01-02 15:04", yr.Date+" "+yr.To, zone)
+		if err != nil {
+			problems = append(problems, fatal("releases", "invalid end "+yr.Date+" "+yr.To))
+			continue
+		}
+		c.Releases = append(c.Releases, Release{
+			Start: start.Unix(),
+			End:   end.Unix(),
+			Flow:  yr.Flow,
+			Label: yr.Level,
+		})
+	}
+
+	sort.Slice(c.Releases, func(i, j int) bool {
+		return c.Releases[i].Start < c.Releases[j].Start
+	})
+	return c, problems
+}
@@ -0,0 +1,158 @@
+package river
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const exampleRiver = `uuid: ccd035ae-9b6c-4bae-b0d0-bfc016d1b8a0
+river: Vyrnwy
+section: 'Lake Vyrnwy to Pont Llogel'
+km: 7
+grade:
+  text: 2/3
+  value: 2.5
+putin:
+  lat: 52.7598188
+  lng: -3.4560005
+takeout:
+  lat: 52.7275735
+  lng: -3.4348434
+measures:
+  -
+    low: 0.4
+    high: 0.8
+    lag: 30m
+    data_url: 'rloi://2003'
+`
+
+const exampleReleases = `desc: 'Example release calendar'
+releases:
+  - date: '2020-07-04'
+    from: '09:00'
+    to: '16:00'
+    flow: 12.5
+`
+
+func writeContent(t *testing.T, dir string, files map[string]string) {
+	for fn, content := range files {
+		fn = filepath.Join(dir, fn)
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeContent(t, dir, map[string]string{
+		"rivers/vyrnwy-lake-vyrnwy-pont-llogel.yaml":   exampleRiver,
+		"releases/vyrnwy-lake-vyrnwy-pont-llogel.yaml": exampleReleases,
+	})
+
+	c, problems, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Error("Unexpected problems", problems)
+	}
+	if len(c.Sections) != 1 || c.Sections[0].Slug != "vyrnwy-lake-vyrnwy-pont-llogel" {
+		t.Fatal("Section not loaded", c.Sections)
+	}
+
+	uuid := c.Sections[0].UUID
+	cals := c.Calibrations[uuid]
+	if len(cals) != 1 || cals[0].Minimum[High.String()] != 0.8 || cals[0].Lag.Minutes() != 30 {
+		t.Error("Calibration not loaded", cals)
+	}
+	releases := c.Releases[uuid].Releases
+	if len(releases) != 1 || releases[0].StartTime().Hour() != 8 {
+		t.Error("Release not loaded in UK local time", releases)
+	}
+}
+
+func TestLoadDirProblems(t *testing.T) {
+	dir, err := ioutil.TempDir("", "content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeContent(t, dir, map[string]string{
+		"rivers/broken.yaml":                         "uuid: [",
+		"rivers/vyrnwy-lake-vyrnwy-pont-llogel.yaml": strings.Replace(exampleRiver, "high: 0.8", "hihg: 0.8", 1),
+		"releases/none.yaml":                         exampleReleases,
+	})
+
+	_, problems, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasFatal(problems[filepath.Join("rivers", "broken.yaml")]) {
+		t.Error("Broken YAML not reported", problems)
+	}
+	if !HasFatal(problems[filepath.Join("releases", "none.yaml")]) {
+		t.Error("Release without a section not reported", problems)
+	}
+	typo := problems[filepath.Join("rivers", "vyrnwy-lake-vyrnwy-pont-llogel.yaml")]
+	if len(typo) != 1 || typo[0].Field != "rloi://2003 hihg" || !typo[0].IsFatal {
+		t.Error("Misspelt threshold not reported", typo)
+	}
+}
+
+func TestLoaderReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeContent(t, dir, map[string]string{
+		"rivers/vyrnwy-lake-vyrnwy-pont-llogel.yaml": exampleRiver,
+	})
+
+	l, _, err := NewLoader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloaded []Content
+	l.OnReload(func(c Content) {
+		reloaded = append(reloaded, c)
+	})
+
+	if hasChanged, _, err := l.Reload(); hasChanged || err != nil {
+		t.Error("Reloaded unchanged content", err)
+	}
+
+	// an invalid edit keeps the current content
+	writeContent(t, dir, map[string]string{
+		"rivers/copy.yaml": exampleRiver,
+	})
+	if hasChanged, _, err := l.Reload(); hasChanged || err == nil {
+		t.Error("Reloaded invalid content")
+	}
+	if len(l.Content().Sections) != 1 {
+		t.Error("Invalid content replaced current content")
+	}
+
+	// a valid edit is reloaded
+	os.Remove(filepath.Join(dir, "rivers", "copy.yaml"))
+	writeContent(t, dir, map[string]string{
+		"releases/vyrnwy-lake-vyrnwy-pont-llogel.yaml": exampleReleases,
+	})
+	if hasChanged, _, err := l.Reload(); !hasChanged || err != nil {
+		t.Error("Valid content not reloaded", err)
+	}
+	if len(reloaded) != 1 || len(reloaded[0].Releases) != 1 {
+		t.Error("Reload hook not called with new content", reloaded)
+	}
+}
@@ -0,0 +1,121 @@
+package river
+
+import (
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+// Operations to derive a virtual gauge from its input gauges
+const (
+	// Sum adds together the inputs, such as the flow of tributaries
+	Sum = "sum"
+	// Difference takes the later inputs from the first, such as a
+	// river level minus a tide level
+	Difference = "difference"
+	// Ratio divides the first input by the second
+	Ratio = "ratio"
+)
+
+// DerivedScheme prefixes the URL of a calibration against a derived gauge,
+// so it cannot be confused with a real station
+const DerivedScheme = "derived://"
+
+// Derivation defines a virtual gauge computed from other gauges
+type Derivation struct {
+	Op     string   `firestore:"op,omitempty" yaml:"op"`
+	Inputs []string `firestore:"inputs,omitempty" yaml:"inputs"`
+}
+
+// IsDerived is true if the calibration is against a derived gauge
+func (c Calibration) IsDerived() bool {
+	return c.Derived.Op != ""
+}
+
+// RouteURLs are the station URLs providing readings for the calibration
+func (c Calibration) RouteURLs() []string {
+	if c.IsDerived() {
+		return c.Derived.Inputs
+	}
+	return []string{c.URL}
+}
+
+// Symbol is the operator used to describe a derived gauge, or blank if the
+// operation is not known
+func (d Derivation) Symbol() string {
+	switch d.Op {
+	case Sum:
+		return "+"
+	case Difference:
+		return "-"
+	case Ratio:
+		return "/"
+	}
+	return ""
+}
+
+// Combine computes the derived readings from the readings of each input in
+// order, all most recent first
+//
+// The readings are aligned to the times of the first input, interpolating
+// the other inputs between their readings. There are no derived readings at
+// times outside the readings of any input.
+func (d Derivation) Combine(inputs [][]gauge.Reading) []gauge.Reading {
+	if len(inputs) == 0 || len(inputs) != len(d.Inputs) {
+		return nil
+	}
+	if d.Op == Ratio && len(inputs) != 2 {
+		return nil
+	}
+
+	var combined []gauge.Reading
+nextReading:
+	for _, r := range inputs[0] {
+		value := r.Value
+		for _, input := range inputs[1:] {
+			v, ok := interpolate(input, r)
+			if !ok {
+				continue nextReading
+			}
+			switch d.Op {
+			case Sum:
+				value += v
+			case Difference:
+				value -= v
+			case Ratio:
+				if v == 0 {
+					continue nextReading
+				}
+				value /= v
+			default:
+				return nil
+			}
+		}
+		combined = append(combined, gauge.Reading{
+			EventTime: r.EventTime,
+			Value:     value,
+		})
+	}
+	return combined
+}
+
+// interpolate the value of readings, most recent first, at the time of
+// another reading
+func interpolate(readings []gauge.Reading, at gauge.Reading) (float32, bool) {
+	for i, r := range readings {
+		if r.EventTime.Equal(at.EventTime) {
+			return r.Value, true
+		}
+		if r.EventTime.After(at.EventTime) {
+			continue
+		}
+		// r is the first reading before the time, so interpolate
+		// towards the reading after it if there is one
+		if i == 0 {
+			return 0, false
+		}
+		next := readings[i-1]
+		span := next.EventTime.Sub(r.EventTime)
+		f := float32(at.EventTime.Sub(r.EventTime)) / float32(span)
+		return r.Value + f*(next.Value-r.Value), true
+	}
+	return 0, false
+}
@@ -0,0 +1,46 @@
+package river
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+func TestCombineDerived(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2016-01-01T10:00:00Z")
+	at := func(minutes int, value float32) gauge.Reading {
+		return gauge.Reading{
+			EventTime: start.Add(time.Duration(minutes) * time.Minute),
+			Value:     value,
+		}
+	}
+	// most recent first, with the second input read half as often and
+	// starting later
+	first := []gauge.Reading{at(60, 4), at(45, 3), at(30, 2), at(15, 1), at(0, 0)}
+	second := []gauge.Reading{at(60, 3), at(30, 1), at(15, 0.5)}
+
+	expect := map[string][]gauge.Reading{
+		Sum:        {at(60, 7), at(45, 5), at(30, 3), at(15, 1.5)},
+		Difference: {at(60, 1), at(45, 1), at(30, 1), at(15, 0.5)},
+		Ratio:      {at(60, 4.0/3), at(45, 1.5), at(30, 2), at(15, 2)},
+	}
+	for op, want := range expect {
+		d := Derivation{Op: op, Inputs: []string{"rloi://1", "rloi://2"}}
+		got := d.Combine([][]gauge.Reading{first, second})
+		if len(got) != len(want) {
+			t.Error(op, "readings mis-match", got)
+			continue
+		}
+		for i := range want {
+			if !got[i].EventTime.Equal(want[i].EventTime) || got[i].Value != want[i].Value {
+				t.Error(op, "reading", i, got[i], "not", want[i])
+			}
+		}
+	}
+
+	unknown := Derivation{Op: "product", Inputs: []string{"rloi://1", "rloi://2"}}
+	if got := unknown.Combine([][]gauge.Reading{first, second}); got != nil {
+		t.Error("Unknown op combined", got)
+	}
+}
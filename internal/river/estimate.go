@@ -0,0 +1,26 @@
+package river
+
+// EstimateAt approximates the level at a value from the typical low and
+// high values of an uncalibrated gauge
+//
+// The typical range is split into quarters from empty to medium, with
+// anything above the range as high. Huge and too high are never estimated
+// as a gauge alone cannot say when a section becomes dangerous.
+func EstimateAt(value float32, low float32, high float32) Level {
+	if high <= low {
+		return Unknown
+	}
+
+	quarter := (high - low) / 4
+	switch {
+	case value < low+quarter:
+		return Empty
+	case value < low+2*quarter:
+		return Scrape
+	case value < low+3*quarter:
+		return Low
+	case value < high:
+		return Medium
+	}
+	return High
+}
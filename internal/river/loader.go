@@ -0,0 +1,87 @@
+package river
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// Loader holds the current content, reloading it from a directory on
+// request so content updates do not need a new binary
+type Loader struct {
+	dir      string
+	mu       sync.RWMutex
+	content  Content
+	checksum string
+	hooks    []func(Content)
+}
+
+// NewLoader loads the content from a directory, failing if it cannot be
+// read or has fatal problems
+func NewLoader(dir string) (*Loader, map[string][]Problem, error) {
+	l := &Loader{dir: dir}
+	_, problems, err := l.Reload()
+	return l, problems, err
+}
+
+// NewStaticLoader provides fixed content that is never reloaded, such as
+// the content generated into a binary
+func NewStaticLoader(c Content) *Loader {
+	return &Loader{content: c}
+}
+
+// Content is the current content
+func (l *Loader) Content() Content {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.content
+}
+
+// OnReload registers a function called with the new content each time it
+// is reloaded
+func (l *Loader) OnReload(fn func(Content)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, fn)
+}
+
+// Reload re-reads the content directory if the files have changed since
+// last loaded
+//
+// The current content is kept if the new content has fatal problems, so a
+// bad edit does not take down a running daemon.
+func (l *Loader) Reload() (hasChanged bool, problems map[string][]Problem, err error) {
+	if l.dir == "" {
+		return false, nil, nil
+	}
+
+	content, problems, checksum, err := loadDir(l.dir)
+	if err != nil {
+		return false, problems, err
+	}
+	nFatal := 0
+	for _, ps := range problems {
+		for _, p := range ps {
+			if p.IsFatal {
+				nFatal++
+			}
+		}
+	}
+	if nFatal > 0 {
+		return false, problems, errors.New(strconv.Itoa(nFatal) + " content errors in " + l.dir)
+	}
+
+	l.mu.Lock()
+	if checksum == l.checksum {
+		l.mu.Unlock()
+		return false, problems, nil
+	}
+	l.content, l.checksum = content, checksum
+	hooks := append([]func(Content){}, l.hooks...)
+	l.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(content)
+	}
+	return true, problems, nil
+}
@@ -0,0 +1,48 @@
+package river
+
+import (
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+// RainfallType is the measurement type of a calibration against rainfall
+// accumulated over a window rather than a level
+const RainfallType = "rainfall"
+
+// DefaultRainfallWindow is the accumulation window of a rainfall
+// calibration that does not specify one
+const DefaultRainfallWindow = 12 * time.Hour
+
+// IsRainfall is true if the minimum values are rainfall totals
+func (c Calibration) IsRainfall() bool {
+	return c.Type == RainfallType
+}
+
+// RainfallWindow is the period rainfall is accumulated over
+func (c Calibration) RainfallWindow() time.Duration {
+	if c.Window > 0 {
+		return c.Window
+	}
+	return DefaultRainfallWindow
+}
+
+// RainfallTotal sums the rainfall readings, most recent first, over the
+// window up to a time
+//
+// Each reading is the rain that fell in the period up to its event time, so
+// a reading is only included if it is within the window.
+func (c Calibration) RainfallTotal(readings []gauge.Reading, at time.Time) float32 {
+	start := at.Add(-c.RainfallWindow())
+	var total float32
+	for _, r := range readings {
+		if r.EventTime.After(at) {
+			continue
+		}
+		if !r.EventTime.After(start) {
+			break
+		}
+		total += r.Value
+	}
+	return total
+}
@@ -0,0 +1,36 @@
+package river
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robtuley/rainchasers/internal/gauge"
+)
+
+func TestRainfallTotal(t *testing.T) {
+	latest, _ := time.Parse(time.RFC3339, "2016-01-01T10:00:00Z")
+	// hourly rainfall, most recent first
+	var readings []gauge.Reading
+	for i, v := range []float32{1, 2, 3, 4, 5} {
+		readings = append(readings, gauge.Reading{
+			EventTime: latest.Add(-time.Duration(i) * time.Hour),
+			Value:     v,
+		})
+	}
+
+	c := Calibration{Type: RainfallType, Window: 3 * time.Hour}
+	if total := c.RainfallTotal(readings, latest); total != 6 {
+		t.Error("Total over the window", total)
+	}
+	if total := c.RainfallTotal(readings, latest.Add(-time.Hour)); total != 9 {
+		t.Error("Total over an earlier window", total)
+	}
+
+	c.Window = 0
+	if total := c.RainfallTotal(readings, latest); total != 15 {
+		t.Error("Total over the default window", total)
+	}
+	if c.IsRainfall() != true || (Calibration{}).IsRainfall() {
+		t.Error("Rainfall type not recognised")
+	}
+}
@@ -0,0 +1,71 @@
+package river
+
+import "time"
+
+// Calendar is the published schedule of releases from a dam upstream of a
+// section, which decides the level more than any gauge
+type Calendar struct {
+	Description string    `firestore:"desc"`
+	URL         string    `firestore:"url,omitempty"`
+	Releases    []Release `firestore:"releases"`
+}
+
+// Release is a single scheduled release, with the times held as unix
+// seconds so the calendar can be generated as a literal
+type Release struct {
+	Start int64   `firestore:"start"`
+	End   int64   `firestore:"end"`
+	Flow  float32 `firestore:"flow,omitempty"`  // expected flow in m3/s, zero if not published
+	Label string  `firestore:"label,omitempty"` // expected level, medium if blank
+}
+
+// StartTime is when the release starts
+func (r Release) StartTime() time.Time {
+	return time.Unix(r.Start, 0).UTC()
+}
+
+// EndTime is when the release ends
+func (r Release) EndTime() time.Time {
+	return time.Unix(r.End, 0).UTC()
+}
+
+// Level is the expected level of the section during the release
+func (r Release) Level() Level {
+	if r.Label == "" {
+		return Medium
+	}
+	return StringToLevel(r.Label)
+}
+
+// At finds the release running at a time
+func (c Calendar) At(t time.Time) (Release, bool) {
+	for _, r := range c.Releases {
+		if !t.Before(r.StartTime()) && t.Before(r.EndTime()) {
+			return r, true
+		}
+	}
+	return Release{}, false
+}
+
+// Next finds the first release to start after a time
+func (c Calendar) Next(t time.Time) (Release, bool) {
+	for _, r := range c.Releases {
+		if r.StartTime().After(t) {
+			return r, true
+		}
+	}
+	return Release{}, false
+}
+
+// NextChange is the next time after t that a release starts or ends
+func (c Calendar) NextChange(t time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, r := range c.Releases {
+		for _, at := range []time.Time{r.StartTime(), r.EndTime()} {
+			if at.After(t) && (next.IsZero() || at.Before(next)) {
+				next = at
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,41 @@
+package river
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReleaseCalendar(t *testing.T) {
+	day := time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC)
+	cal := Calendar{Releases: []Release{
+		{Start: day.Add(8 * time.Hour).Unix(), End: day.Add(15 * time.Hour).Unix()},
+		{Start: day.Add(32 * time.Hour).Unix(), End: day.Add(39 * time.Hour).Unix(), Label: "high"},
+	}}
+
+	if _, running := cal.At(day.Add(7 * time.Hour)); running {
+		t.Error("Release running before start")
+	}
+	if r, running := cal.At(day.Add(8 * time.Hour)); !running || r.Level() != Medium {
+		t.Error("Release not running at start", r)
+	}
+	if _, running := cal.At(day.Add(15 * time.Hour)); running {
+		t.Error("Release running at end")
+	}
+
+	if r, ok := cal.Next(day.Add(9 * time.Hour)); !ok || r.Level() != High {
+		t.Error("Bad next release", r)
+	}
+	if _, ok := cal.Next(day.Add(33 * time.Hour)); ok {
+		t.Error("Next release after the last")
+	}
+
+	for hours, want := range map[int]int{0: 8, 8: 15, 9: 15, 15: 32, 35: 39} {
+		at, ok := cal.NextChange(day.Add(time.Duration(hours) * time.Hour))
+		if !ok || !at.Equal(day.Add(time.Duration(want)*time.Hour)) {
+			t.Error("Bad next change", hours, at)
+		}
+	}
+	if _, ok := cal.NextChange(day.Add(39 * time.Hour)); ok {
+		t.Error("Change after the last release")
+	}
+}
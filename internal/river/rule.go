@@ -0,0 +1,30 @@
+package river
+
+// Rule defines how the levels from several calibrated gauges on the same
+// section are combined into a single section level
+type Rule string
+
+const (
+	// Primary uses the first calibrated gauge, falling back to the next
+	// gauge in order when it has no recent readings
+	Primary Rule = "primary"
+	// Latest uses whichever gauge has the most recent reading
+	Latest Rule = "latest"
+	// Worst uses the highest level of any gauge to err on the side of caution
+	Worst Rule = "worst"
+	// Agree requires all gauges with recent readings to report the same level
+	Agree Rule = "agree"
+)
+
+// StringToRule converts a string to a rule, defaulting to Primary
+func StringToRule(str string) Rule {
+	switch Rule(str) {
+	case Latest:
+		return Latest
+	case Worst:
+		return Worst
+	case Agree:
+		return Agree
+	}
+	return Primary
+}
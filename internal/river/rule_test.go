@@ -0,0 +1,21 @@
+package river
+
+import (
+	"testing"
+)
+
+func TestStringToRule(t *testing.T) {
+	expect := map[string]Rule{
+		"":        Primary,
+		"primary": Primary,
+		"latest":  Latest,
+		"worst":   Worst,
+		"agree":   Agree,
+		"unknown": Primary,
+	}
+	for str, rule := range expect {
+		if r := StringToRule(str); r != rule {
+			t.Error(str, "is rule", r)
+		}
+	}
+}
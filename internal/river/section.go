@@ -25,4 +25,11 @@ type Section struct {
 	Takeout     LatLng  `firestore:"takeout" yaml:"takeout"`
 	Description string  `firestore:"desc" yaml:"desc"`
 	Directions  string  `firestore:"directions" yaml:"directions"`
+
+	// LevelRule combines levels when more than one gauge is calibrated
+	LevelRule Rule `firestore:"level_rule,omitempty" yaml:"level_rule,omitempty"`
+
+	// Aliases are the previous slugs of a renamed section, so old links
+	// can be redirected to the current slug
+	Aliases []string `firestore:"aliases,omitempty" yaml:"aliases,omitempty"`
 }
@@ -0,0 +1,23 @@
+package river
+
+// Trend represents the direction a river level is moving
+type Trend int
+
+const (
+	// Steady means the level is not changing significantly
+	Steady Trend = 0
+	// Rising means the level is going up
+	Rising Trend = 1
+	// Falling means the level is going down
+	Falling Trend = -1
+)
+
+func (t Trend) String() string {
+	switch t {
+	case Rising:
+		return "rising"
+	case Falling:
+		return "falling"
+	}
+	return "steady"
+}
@@ -0,0 +1,48 @@
+package river
+
+import "strings"
+
+// unitScales maps a unit name to its base unit and the multiple of the
+// base unit it represents
+var unitScales = map[string]struct {
+	Base  string
+	Scale float64
+}{
+	"m":      {"m", 1},
+	"masd":   {"m", 1},
+	"metre":  {"m", 1},
+	"metres": {"m", 1},
+	"cm":     {"m", 0.01},
+	"mm":     {"m", 0.001},
+	"m3/s":   {"m3/s", 1},
+	"m3s-1":  {"m3/s", 1},
+	"cumecs": {"m3/s", 1},
+	"l/s":    {"m3/s", 0.001},
+}
+
+// IsKnownUnit is true if a unit can be converted
+func IsKnownUnit(unit string) bool {
+	_, ok := unitScales[normaliseUnit(unit)]
+	return ok
+}
+
+// ConvertUnit converts a value between units, returning false if the units
+// do not measure the same thing or are not known
+//
+// Stage levels in mASD are treated as metres, but levels relative to a
+// different datum such as mAOD are not convertible.
+func ConvertUnit(value float32, from string, to string) (float32, bool) {
+	f, ok := unitScales[normaliseUnit(from)]
+	if !ok {
+		return 0, false
+	}
+	t, ok := unitScales[normaliseUnit(to)]
+	if !ok || f.Base != t.Base {
+		return 0, false
+	}
+	return float32(float64(value) * f.Scale / t.Scale), true
+}
+
+func normaliseUnit(unit string) string {
+	return strings.ToLower(strings.TrimSpace(unit))
+}
@@ -0,0 +1,37 @@
+package river
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertUnit(t *testing.T) {
+	expect := []struct {
+		From  string
+		To    string
+		Value float32
+		Want  float32
+	}{
+		{"m", "mASD", 1.2, 1.2},
+		{"mm", "m", 1200, 1.2},
+		{"m", "mm", 1.2, 1200},
+		{"cumecs", "m3/s", 15, 15},
+		{"l/s", "cumecs", 1500, 1.5},
+	}
+	for _, e := range expect {
+		v, ok := ConvertUnit(e.Value, e.From, e.To)
+		if !ok {
+			t.Error("Cannot convert", e.From, "to", e.To)
+			continue
+		}
+		if math.Abs(float64(v-e.Want)) > 0.0001 {
+			t.Error(e.Value, e.From, "is", v, e.To, "not", e.Want)
+		}
+	}
+
+	for _, units := range [][2]string{{"m", "m3/s"}, {"mAOD", "m"}, {"m", ""}} {
+		if _, ok := ConvertUnit(1, units[0], units[1]); ok {
+			t.Error("Converted", units[0], "to", units[1])
+		}
+	}
+}
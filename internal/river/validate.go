@@ -0,0 +1,282 @@
+package river
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Problem is an issue found validating river content
+type Problem struct {
+	Field   string // e.g. "takeout"
+	Message string // e.g. "is 0,0"
+	IsFatal bool   // fatal problems fail content generation
+}
+
+func (p Problem) String() string {
+	if p.IsFatal {
+		return p.Field + ": " + p.Message
+	}
+	return p.Field + ": " + p.Message + " (warning)"
+}
+
+// HasFatal is true if any of the problems is fatal
+func HasFatal(problems []Problem) bool {
+	for _, p := range problems {
+		if p.IsFatal {
+			return true
+		}
+	}
+	return false
+}
+
+func fatal(field string, message string) Problem {
+	return Problem{Field: field, Message: message, IsFatal: true}
+}
+
+func warning(field string, message string) Problem {
+	return Problem{Field: field, Message: message}
+}
+
+// ukBounds is a box around the UK & Ireland that every location is within
+var ukBounds = struct {
+	MinLat, MaxLat, MinLng, MaxLng float32
+}{49.8, 61, -10.7, 2}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// ValidateSections checks every section, and that no two sections share a
+// UUID, slug or alias, with the problems keyed by section slug
+func ValidateSections(sections []Section) map[string][]Problem {
+	problems := make(map[string][]Problem)
+	uuids := make(map[string]string)
+	slugs := make(map[string]bool)
+	for _, s := range sections {
+		ps := s.Validate()
+		if other, exists := uuids[s.UUID]; exists && s.UUID != "" {
+			ps = append(ps, fatal("uuid", "duplicates "+other))
+		}
+		if slugs[s.Slug] {
+			ps = append(ps, fatal("slug", "duplicates another section"))
+		}
+		uuids[s.UUID] = s.Slug
+		slugs[s.Slug] = true
+		if len(ps) > 0 {
+			problems[s.Slug] = append(problems[s.Slug], ps...)
+		}
+	}
+
+	// an alias must not shadow a current slug, or redirect to two sections
+	aliases := make(map[string]string)
+	for _, s := range sections {
+		for _, alias := range s.Aliases {
+			var p Problem
+			switch other, exists := aliases[alias]; {
+			case slugs[alias]:
+				p = fatal("aliases", alias+" is the slug of a section")
+			case exists && other != s.Slug:
+				p = fatal("aliases", alias+" is also an alias of "+other)
+			case exists:
+				p = fatal("aliases", alias+" is repeated")
+			default:
+				aliases[alias] = s.Slug
+				continue
+			}
+			problems[s.Slug] = append(problems[s.Slug], p)
+		}
+	}
+	return problems
+}
+
+// Validate checks a section definition
+func (s Section) Validate() []Problem {
+	var problems []Problem
+	if !uuidPattern.MatchString(s.UUID) {
+		problems = append(problems, fatal("uuid", "is not a lowercase UUID"))
+	}
+	if strings.TrimSpace(s.RiverName) == "" {
+		problems = append(problems, fatal("river", "is blank"))
+	}
+	if strings.TrimSpace(s.SectionName) == "" {
+		problems = append(problems, fatal("section", "is blank"))
+	}
+	if s.KM < 0 {
+		problems = append(problems, fatal("km", "is negative"))
+	}
+	if s.LevelRule != "" && s.LevelRule != Primary && StringToRule(string(s.LevelRule)) == Primary {
+		problems = append(problems, fatal("level_rule", "is not known"))
+	}
+	for _, alias := range s.Aliases {
+		if !slugPattern.MatchString(alias) {
+			problems = append(problems, fatal("aliases", alias+" is not a lowercase slug"))
+		}
+	}
+	problems = append(problems, s.Putin.validate("putin", true)...)
+	problems = append(problems, s.Takeout.validate("takeout", false)...)
+	problems = append(problems, s.Grade.Validate()...)
+	return problems
+}
+
+// validate checks a location is set and in the UK, a missing location only
+// being fatal if it is required
+func (l LatLng) validate(field string, isRequired bool) []Problem {
+	if l.Lat == 0 && l.Lng == 0 {
+		if isRequired {
+			return []Problem{fatal(field, "is 0,0")}
+		}
+		return []Problem{warning(field, "is 0,0")}
+	}
+	if l.Lat < ukBounds.MinLat || l.Lat > ukBounds.MaxLat {
+		return []Problem{fatal(field+".lat", "is outside the UK")}
+	}
+	if l.Lng < ukBounds.MinLng || l.Lng > ukBounds.MaxLng {
+		return []Problem{fatal(field+".lng", "is outside the UK")}
+	}
+	return nil
+}
+
+// gradePattern matches a grade such as "3", "2/3", "3 (4)" or "3/4 (5+)"
+var gradePattern = regexp.MustCompile(`^([1-6])(?:/([1-6]))?(?: \(([1-6])([+-]?)\))?$`)
+
+// Validate checks the grade text is understood and the numeric grades are
+// consistent with it
+func (g Grade) Validate() []Problem {
+	m := gradePattern.FindStringSubmatch(g.Human)
+	if m == nil {
+		return []Problem{fatal("grade.text", "is not understood: "+g.Human)}
+	}
+	low, _ := strconv.ParseFloat(m[1], 32)
+	high := low
+	if m[2] != "" {
+		high, _ = strconv.ParseFloat(m[2], 32)
+	}
+
+	var problems []Problem
+	if high < low {
+		problems = append(problems, fatal("grade.text", "range is reversed: "+g.Human))
+	}
+	if v := float64(g.Average); v < low || v > high {
+		problems = append(problems, fatal("grade.value", "is not within "+g.Human))
+	}
+	if g.Max != 0 && !isWithinMax(float64(g.Max), high, m[3], m[4]) {
+		problems = append(problems, fatal("grade.max", "does not match "+g.Human))
+	}
+	return problems
+}
+
+// isWithinMax is true if a numeric max grade matches the hardest grade in
+// the text, with a "+" or "-" grade anywhere between the whole grades
+func isWithinMax(max float64, high float64, text string, modifier string) bool {
+	if text == "" {
+		return max == high
+	}
+	grade, _ := strconv.ParseFloat(text, 32)
+	switch modifier {
+	case "+":
+		return max > grade && max < grade+1
+	case "-":
+		return max > grade-1 && max < grade
+	}
+	return max == grade
+}
+
+// Validate checks a calibration is usable, with the field names prefixed by
+// the URL of the gauge
+func (c Calibration) Validate() []Problem {
+	var problems []Problem
+	field := func(name string) string {
+		return c.URL + " " + name
+	}
+
+	switch c.Type {
+	case "", "level", "flow", RainfallType:
+	default:
+		problems = append(problems, fatal(field("type"), "is not supported: "+c.Type))
+	}
+	if c.Unit != "" && !IsKnownUnit(c.Unit) {
+		problems = append(problems, fatal(field("unit"), "is not known: "+c.Unit))
+	}
+	if c.Estimate && len(c.Minimum) > 0 {
+		problems = append(problems, fatal(field("estimate"), "cannot have thresholds"))
+	}
+	if c.Estimate && c.IsRainfall() {
+		problems = append(problems, fatal(field("estimate"), "cannot be rainfall"))
+	}
+	if c.Window != 0 && !c.IsRainfall() {
+		problems = append(problems, fatal(field("window"), "is only for rainfall"))
+	}
+	if c.Window < 0 {
+		problems = append(problems, fatal(field("window"), "is negative"))
+	}
+	if c.IsDerived() || strings.HasPrefix(c.URL, DerivedScheme) {
+		problems = append(problems, c.validateDerived()...)
+	}
+
+	// a misspelt level would otherwise silently disable its threshold
+	known := make(map[string]bool)
+	for lvl := Empty; lvl <= TooHigh; lvl++ {
+		known[lvl.String()] = true
+	}
+	var unknown []string
+	for key := range c.Minimum {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fatal(field(key), "is not a level"))
+	}
+
+	// each threshold must be above those of lower levels
+	var prevLevel Level
+	var prevValue float32
+	hasPrev := false
+	for lvl := Empty; lvl <= TooHigh; lvl++ {
+		v, exists := c.Minimum[lvl.String()]
+		if !exists {
+			continue
+		}
+		if hasPrev && v <= prevValue {
+			problems = append(problems, fatal(field(lvl.String()), "is not above "+prevLevel.String()))
+		}
+		prevLevel, prevValue, hasPrev = lvl, v, true
+	}
+	return problems
+}
+
+func (c Calibration) validateDerived() []Problem {
+	field := c.URL + " derived"
+	if !strings.HasPrefix(c.URL, DerivedScheme) {
+		return []Problem{fatal(field, "must have a "+DerivedScheme+" URL")}
+	}
+	if c.Derived.Symbol() == "" {
+		return []Problem{fatal(field+".op", "is not known: "+c.Derived.Op)}
+	}
+	if len(c.Derived.Inputs) < 2 || (c.Derived.Op == Ratio && len(c.Derived.Inputs) != 2) {
+		return []Problem{fatal(field+".inputs", "is the wrong number for "+c.Derived.Op)}
+	}
+	return nil
+}
+
+// Validate checks the releases are in order, do not overlap, and have a
+// known level
+func (c Calendar) Validate() []Problem {
+	var problems []Problem
+	for i, r := range c.Releases {
+		day := r.StartTime().Format("2006-01-02")
+		if r.End <= r.Start {
+			problems = append(problems, fatal("releases "+day, "ends before it starts"))
+		}
+		if r.Label != "" && r.Level() == Unknown {
+			problems = append(problems, fatal("releases "+day, "has unknown level "+r.Label))
+		}
+		if i > 0 && r.Start < c.Releases[i-1].End {
+			problems = append(problems, fatal("releases "+day, "overlaps the previous release"))
+		}
+	}
+	return problems
+}
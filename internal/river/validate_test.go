@@ -0,0 +1,136 @@
+package river
+
+import (
+	"testing"
+	"time"
+)
+
+func validSection() Section {
+	return Section{
+		UUID:        "ccd035ae-9b6c-4bae-b0d0-bfc016d1b8a0",
+		Slug:        "vyrnwy-lake-vyrnwy-pont-llogel",
+		SectionName: "Lake Vyrnwy to Pont Llogel",
+		RiverName:   "Vyrnwy",
+		KM:          7,
+		Grade:       Grade{Human: "2/3 (4-)", Average: 2.5, Max: 3.8},
+		Putin:       LatLng{Lat: 52.7598188, Lng: -3.4560005},
+		Takeout:     LatLng{Lat: 52.7275735, Lng: -3.4348434},
+	}
+}
+
+func fields(problems []Problem) map[string]bool {
+	found := make(map[string]bool)
+	for _, p := range problems {
+		found[p.Field] = p.IsFatal
+	}
+	return found
+}
+
+func TestValidSection(t *testing.T) {
+	if problems := validSection().Validate(); len(problems) > 0 {
+		t.Error("Unexpected problems", problems)
+	}
+}
+
+func TestSectionProblems(t *testing.T) {
+	s := validSection()
+	s.Takeout = LatLng{}
+	s.Putin.Lng = 12.5
+	s.Grade = Grade{Human: "3 (4)", Average: 2.5, Max: 4.5}
+
+	found := fields(s.Validate())
+	for field, isFatal := range map[string]bool{
+		"takeout":     false,
+		"putin.lng":   true,
+		"grade.value": true,
+		"grade.max":   true,
+	} {
+		if fatal, exists := found[field]; !exists || fatal != isFatal {
+			t.Error("Problem not found", field, found)
+		}
+	}
+	if len(found) != 4 {
+		t.Error("Unexpected problems", found)
+	}
+}
+
+func TestDuplicateSections(t *testing.T) {
+	a := validSection()
+	b := validSection()
+	b.Slug = "copy"
+
+	problems := ValidateSections([]Section{a, b})
+	if len(problems[a.Slug]) != 0 {
+		t.Error("First section has problems", problems[a.Slug])
+	}
+	if !HasFatal(problems["copy"]) || problems["copy"][0].Message != "duplicates "+a.Slug {
+		t.Error("Duplicate UUID not found", problems["copy"])
+	}
+}
+
+func TestAliasCollisions(t *testing.T) {
+	a := validSection()
+	a.Aliases = []string{"vyrnwy-old-name", "Vyrnwy Lake"}
+	b := validSection()
+	b.UUID = "14be0011-a293-4e0d-89df-c0216cf9fe5e"
+	b.Slug = "aeron-cilau-aeron-aberaeron"
+	b.Aliases = []string{"vyrnwy-old-name", "vyrnwy-lake-vyrnwy-pont-llogel"}
+
+	problems := ValidateSections([]Section{a, b})
+	if len(problems[a.Slug]) != 1 || problems[a.Slug][0].Message != "Vyrnwy Lake is not a lowercase slug" {
+		t.Error("Invalid alias not found", problems[a.Slug])
+	}
+	found := make(map[string]bool)
+	for _, p := range problems[b.Slug] {
+		found[p.Message] = p.IsFatal
+	}
+	if !found["vyrnwy-old-name is also an alias of "+a.Slug] || !found[a.Slug+" is the slug of a section"] || len(found) != 2 {
+		t.Error("Alias collisions not found", problems[b.Slug])
+	}
+}
+
+func TestCalibrationThresholdsMustIncrease(t *testing.T) {
+	c := Calibration{
+		URL: "rloi://2003",
+		Minimum: map[string]float32{
+			Low.String():    0.4,
+			Medium.String(): 0.3,
+			High.String():   0.8,
+		},
+	}
+	found := fields(c.Validate())
+	if !found["rloi://2003 medium"] || len(found) != 1 {
+		t.Error("Unexpected problems", found)
+	}
+
+	c.Minimum[Medium.String()] = 0.6
+	if problems := c.Validate(); len(problems) > 0 {
+		t.Error("Unexpected problems", problems)
+	}
+}
+
+func TestCalibrationUnknownThreshold(t *testing.T) {
+	c := Calibration{
+		URL: "rloi://2003",
+		Minimum: map[string]float32{
+			Low.String():  0.4,
+			"meduim":      0.6,
+			High.String(): 0.8,
+		},
+	}
+	found := fields(c.Validate())
+	if !found["rloi://2003 meduim"] || len(found) != 1 {
+		t.Error("Unknown threshold not found", found)
+	}
+}
+
+func TestOverlappingReleases(t *testing.T) {
+	day := time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC)
+	cal := Calendar{Releases: []Release{
+		{Start: day.Add(8 * time.Hour).Unix(), End: day.Add(15 * time.Hour).Unix()},
+		{Start: day.Add(14 * time.Hour).Unix(), End: day.Add(16 * time.Hour).Unix(), Label: "wild"},
+	}}
+	if problems := cal.Validate(); len(problems) != 2 {
+		t.Error("Unexpected problems", problems)
+	}
+}